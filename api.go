@@ -0,0 +1,238 @@
+package releasekit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const linkNextRegex = `<([^>]+)>;\s*rel="next"`
+
+// ResponseError is an error response from the API of a provider other than
+// GitHub.
+type ResponseError struct {
+	Response *http.Response
+	Message  string
+}
+
+// Error returns the error message.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Response.Request.Method, e.Response.Request.URL, e.Response.StatusCode, e.Message)
+}
+
+// apiClient makes requests to a JSON REST API, such as the GitLab or Gitea API.
+type apiClient struct {
+	baseURL *url.URL
+	client  *http.Client
+	header  http.Header
+}
+
+// newAPIClient creates a new API client for the base URL, which has a trailing
+// slash added if missing. Requests are made with the header.
+func newAPIClient(baseURL string, client *http.Client, header http.Header) (*apiClient, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &apiClient{baseURL: u, client: client, header: header}, nil
+}
+
+// newRequest creates a new request for the path relative to the base URL, or
+// for an absolute URL. The body is encoded as JSON, unless it is an io.Reader.
+func (c *apiClient) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	contentType := ""
+
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		r = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.ResolveReference(ref).String(), r)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return req, nil
+}
+
+// do makes the request, decoding the JSON response into v if not nil. Error
+// responses are returned as a *ResponseError, wrapped with the kind of error
+// using notFound as the kind for not found responses.
+func (c *apiClient) do(req *http.Request, v interface{}, notFound error) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp, wrapResponseError(&ResponseError{Response: resp, Message: errorMessage(data)}, notFound)
+	}
+
+	if v == nil {
+		discardBody(resp)
+		return resp, nil
+	}
+
+	return resp, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// get makes a GET request for the path, decoding the JSON response into v.
+func (c *apiClient) get(ctx context.Context, path string, v interface{}, notFound error) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req, v, notFound)
+}
+
+// send makes a request for the path with the body encoded as JSON, decoding the
+// JSON response into v if not nil.
+func (c *apiClient) send(ctx context.Context, method, path string, body, v interface{}, notFound error) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.do(req, v, notFound)
+}
+
+// upload makes a POST request for the path with the content as a file named
+// name in the multipart form field, decoding the JSON response into v. The
+// content is streamed, so the request is not retried.
+func (c *apiClient) upload(ctx context.Context, path, field, name string, content io.Reader, v interface{}, notFound error) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		part, err := mw.CreateFormFile(field, name)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+
+		if err == nil {
+			err = mw.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, pr)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	return c.do(req, v, notFound)
+}
+
+// nextPage returns the URL of the next page of results given in the Link header
+// of the response, or an empty string if it is the last page.
+func nextPage(resp *http.Response) string {
+	r, _ := regexp.Compile(linkNextRegex)
+
+	matches := r.FindStringSubmatch(resp.Header.Get("Link"))
+	if matches == nil {
+		return ""
+	}
+
+	return matches[1]
+}
+
+// escapePath escapes each segment of the file path for use in a URL path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// errorMessage gets the message from a JSON error response body, or uses the
+// body as is.
+func errorMessage(data []byte) string {
+	var body struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+
+	if err := json.Unmarshal(data, &body); err != nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	if body.Message != nil {
+		return fmt.Sprint(body.Message)
+	}
+
+	return body.Error
+}
+
+// wrapResponseError wraps the error response with the kind of error, using
+// notFound as the kind for not found responses.
+func wrapResponseError(err *ResponseError, notFound error) error {
+	var kind error
+
+	switch err.Response.StatusCode {
+	case http.StatusNotFound:
+		kind = notFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrPermissionDenied
+	case http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	}
+
+	if kind == nil {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
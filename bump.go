@@ -0,0 +1,129 @@
+package releasekit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bump is the type of semantic version increment for a release.
+type Bump int
+
+// The types of semantic version increment, in increasing order of
+// significance.
+const (
+	BumpNone Bump = iota
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+// DefaultMajorLabels are the labels (or conventional commit types) of changes
+// that require a major version increment.
+var DefaultMajorLabels = []string{BreakingLabel, "major"}
+
+// DefaultMinorLabels are the labels (or conventional commit types) of changes
+// that require a minor version increment.
+var DefaultMinorLabels = []string{"feature", "enhancement", "feat", "minor"}
+
+// String returns the name of the version increment.
+func (b Bump) String() string {
+	switch b {
+	case BumpMajor:
+		return "major"
+	case BumpMinor:
+		return "minor"
+	case BumpPatch:
+		return "patch"
+	}
+
+	return "none"
+}
+
+// DetermineBump determines the version increment required by the items. Items
+// with any of the major labels require a major increment, items with any of the
+// minor labels require a minor increment, and any other items require a patch
+// increment.
+func DetermineBump(items []*Item, major, minor []string) Bump {
+	bump := BumpNone
+
+	for _, item := range items {
+		b := BumpPatch
+
+		switch {
+		case item.Breaking || item.hasAnyLabel(major):
+			b = BumpMajor
+		case item.hasAnyLabel(minor):
+			b = BumpMinor
+		}
+
+		if b > bump {
+			bump = b
+		}
+	}
+
+	return bump
+}
+
+// LatestVersion returns the greatest version, optionally ignoring prerelease
+// versions, or nil if there are no versions.
+func LatestVersion(versions []*Version, prereleases bool) *Version {
+	var latest *Version
+
+	for _, v := range versions {
+		if v.IsPrerelease() && !prereleases {
+			continue
+		}
+
+		if latest == nil || v.Compare(latest) > 0 {
+			latest = v
+		}
+	}
+
+	return latest
+}
+
+// NextVersion computes the next version by incrementing the latest stable
+// version. If the prerelease identifier is given, the next version is a
+// prerelease in the format <version>-<id>.<n>, where n is one more than the
+// greatest existing prerelease for that version and identifier.
+func NextVersion(versions []*Version, bump Bump, id string) *Version {
+	base := LatestVersion(versions, false)
+	if base == nil {
+		base = &Version{Prefix: "v"}
+
+		if latest := LatestVersion(versions, true); latest != nil {
+			base.Prefix = latest.Prefix
+		}
+	}
+
+	if bump == BumpNone {
+		bump = BumpPatch
+	}
+
+	next := base.Bump(bump)
+
+	if id == "" {
+		return next
+	}
+
+	n := 0
+
+	for _, v := range versions {
+		if v.Major != next.Major || v.Minor != next.Minor || v.Patch != next.Patch {
+			continue
+		}
+
+		if !strings.HasPrefix(v.Prerelease, id+".") {
+			continue
+		}
+
+		if i, err := strconv.Atoi(strings.TrimPrefix(v.Prerelease, id+".")); err == nil && i > n {
+			n = i
+		}
+	}
+
+	next.Prerelease = fmt.Sprintf("%s.%d", id, n+1)
+
+	return next
+}
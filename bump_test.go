@@ -0,0 +1,79 @@
+package releasekit
+
+import "testing"
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		bump Bump
+		id   string
+		want string
+	}{
+		{
+			name: "no tags",
+			bump: BumpMinor,
+			want: "v0.1.0",
+		},
+		{
+			name: "patch",
+			tags: []string{"v1.0.0", "v1.2.3", "v1.1.0"},
+			bump: BumpPatch,
+			want: "v1.2.4",
+		},
+		{
+			name: "minor",
+			tags: []string{"v1.2.3"},
+			bump: BumpMinor,
+			want: "v1.3.0",
+		},
+		{
+			name: "major",
+			tags: []string{"v1.2.3"},
+			bump: BumpMajor,
+			want: "v2.0.0",
+		},
+		{
+			name: "none is a patch",
+			tags: []string{"v1.2.3"},
+			bump: BumpNone,
+			want: "v1.2.4",
+		},
+		{
+			name: "prefix of the latest version",
+			tags: []string{"1.2.3", "not-a-version"},
+			bump: BumpPatch,
+			want: "1.2.4",
+		},
+		{
+			name: "prereleases are ignored",
+			tags: []string{"v1.2.3", "v2.0.0-rc.1"},
+			bump: BumpMinor,
+			want: "v1.3.0",
+		},
+		{
+			name: "first prerelease",
+			tags: []string{"v1.2.3"},
+			bump: BumpMinor,
+			id:   "rc",
+			want: "v1.3.0-rc.1",
+		},
+		{
+			name: "next prerelease",
+			tags: []string{"v1.2.3", "v1.3.0-rc.1", "v1.3.0-rc.2", "v1.3.0-beta.5", "v1.4.0-rc.7"},
+			bump: BumpMinor,
+			id:   "rc",
+			want: "v1.3.0-rc.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NextVersion(VersionTags(tt.tags, ""), tt.bump, tt.id)
+
+			if got.String() != tt.want {
+				t.Errorf("NextVersion(%v, %s, %q) = %s, want %s", tt.tags, tt.bump, tt.id, got, tt.want)
+			}
+		})
+	}
+}
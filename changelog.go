@@ -0,0 +1,271 @@
+package releasekit
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	changelogHeadingRegex = `^## \[?([^\]\s]+)\]?(?:\s+-\s+(\S+))?`
+	changelogLinkRegex    = `^\[([^\]]+)\]: `
+
+	changelogUnreleasedLinkRegex = `(?i)^(\[unreleased\]: \S*/compare/)(\S+?)(\.\.\.HEAD)$`
+)
+
+// DefaultChangelogHeader is the header of a new changelog.
+const DefaultChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.0.0/),
+and this project adheres to [Semantic Versioning](https://semver.org/spec/v2.0.0.html).
+`
+
+// DefaultChangelogTemplate is the template used to render the release notes
+// as a Keep a Changelog style section.
+const DefaultChangelogTemplate = `{{- define "entry" -}}
+- {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Title }}
+{{- if .Number }} ([{{ .Ref }}]({{ .URL }})){{ end }}
+{{- if .Fixes }} (fixes {{ range $i, $issue := .Fixes }}{{ if $i }}, {{ end }}[{{ .Ref }}]({{ .URL }}){{ end }}){{ end }}
+{{- if .FixedBy }} (fixed by {{ range $i, $pr := .FixedBy }}{{ if $i }}, {{ end }}[{{ .Ref }}]({{ .URL }}){{ end }}){{ end }}
+{{- if .SHA }} ([{{ .ShortSHA }}]({{ .CommitURL }})){{ end }} (@{{ .Author }})
+{{ end -}}
+
+## [{{ .Next }}] - {{ .Date.Format "2006-01-02" }}
+{{ if .Sections -}}
+{{ range .Sections }}
+### {{ .Title }}
+{{ range .Items }}{{ template "entry" . }}{{ end -}}
+{{ end -}}
+{{ else if .Items }}
+### Changes
+{{ range .Items }}{{ template "entry" . }}{{ end -}}
+{{ end -}}`
+
+// ChangelogSection is the section for a single version in a changelog.
+type ChangelogSection struct {
+	Version string
+	Date    string
+	Body    string
+}
+
+// changelogHeading is the line index, version and date of a section heading in
+// a changelog.
+type changelogHeading struct {
+	line    int
+	version string
+	date    string
+}
+
+// changelogHeadings finds the section headings in the changelog lines.
+func changelogHeadings(lines []string) []changelogHeading {
+	r, _ := regexp.Compile(changelogHeadingRegex)
+
+	var headings []changelogHeading
+
+	for i, line := range lines {
+		if matches := r.FindStringSubmatch(line); matches != nil {
+			headings = append(headings, changelogHeading{line: i, version: matches[1], date: matches[2]})
+		}
+	}
+
+	return headings
+}
+
+// ParseChangelog parses the Keep a Changelog formatted changelog into the
+// sections for each version, in the order they appear. The body of each section
+// excludes the heading and any link references.
+func ParseChangelog(changelog string) []*ChangelogSection {
+	lines := strings.Split(strings.Replace(changelog, "\r\n", "\n", -1), "\n")
+	headings := changelogHeadings(lines)
+
+	var sections []*ChangelogSection
+
+	for i, heading := range headings {
+		end := sectionEnd(lines, headings, i)
+		body := strings.Join(lines[heading.line+1:end], "\n")
+
+		sections = append(sections, &ChangelogSection{
+			Version: heading.version,
+			Date:    heading.date,
+			Body:    strings.TrimSpace(body),
+		})
+	}
+
+	return sections
+}
+
+// IsUnreleased returns true if the section is for unreleased changes.
+func (s *ChangelogSection) IsUnreleased() bool {
+	return strings.EqualFold(s.Version, "unreleased")
+}
+
+// sameVersion returns true if the changelog versions are the same, ignoring
+// any "v" prefix.
+func sameVersion(a, b string) bool {
+	return strings.TrimPrefix(a, "v") == strings.TrimPrefix(b, "v")
+}
+
+// newerVersion returns true if the version should be listed before the other
+// version in a changelog. Versions that are not semantic versions are assumed
+// to be older, apart from Unreleased.
+func newerVersion(version, other string) bool {
+	if strings.EqualFold(other, "unreleased") {
+		return false
+	}
+
+	a, err := ParseVersion(version)
+	if err != nil {
+		return true
+	}
+
+	b, err := ParseVersion(other)
+	if err != nil {
+		return true
+	}
+
+	return a.Compare(b) > 0
+}
+
+// InsertChangelogSection inserts the section for the version into the
+// changelog, replacing the existing section if the version is already in the
+// changelog. New sections are inserted after the Unreleased section, before the
+// first section of an older version, or at the end. An empty changelog is given
+// the DefaultChangelogHeader. The Unreleased section is left as it is.
+func InsertChangelogSection(changelog, version, section string) string {
+	if strings.TrimSpace(changelog) == "" {
+		changelog = DefaultChangelogHeader
+	}
+
+	lines := strings.Split(strings.TrimRight(changelog, "\n"), "\n")
+
+	sectionLines := strings.Split(strings.TrimRight(section, "\n"), "\n")
+	sectionLines = append(sectionLines, "")
+
+	headings := changelogHeadings(lines)
+
+	start, end := -1, -1
+
+	for i, heading := range headings {
+		if sameVersion(heading.version, version) {
+			start, end = heading.line, sectionEnd(lines, headings, i)
+			break
+		}
+	}
+
+	if start == -1 {
+		for _, heading := range headings {
+			if newerVersion(version, heading.version) {
+				start, end = heading.line, heading.line
+				break
+			}
+		}
+	}
+
+	if start == -1 {
+		start, end = firstLink(lines), firstLink(lines)
+
+		if start == len(lines) {
+			sectionLines = append([]string{""}, sectionLines[:len(sectionLines)-1]...)
+		}
+	}
+
+	var output []string
+	output = append(output, lines[:start]...)
+	output = append(output, sectionLines...)
+	output = append(output, lines[end:]...)
+
+	return strings.TrimRight(strings.Join(output, "\n"), "\n") + "\n"
+}
+
+// sectionEnd returns the line index of the end of the section at the given
+// heading, which is the next heading or the first link reference.
+func sectionEnd(lines []string, headings []changelogHeading, i int) int {
+	end := len(lines)
+	if i+1 < len(headings) {
+		end = headings[i+1].line
+	}
+
+	r, _ := regexp.Compile(changelogLinkRegex)
+
+	for j := headings[i].line + 1; j < end; j++ {
+		if r.MatchString(lines[j]) {
+			return j
+		}
+	}
+
+	return end
+}
+
+// firstLink returns the line index of the first link reference after the last
+// heading, or the number of lines if there are none.
+func firstLink(lines []string) int {
+	r, _ := regexp.Compile(changelogLinkRegex)
+
+	first := len(lines)
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "#") {
+			break
+		}
+
+		if r.MatchString(lines[i]) {
+			first = i
+		}
+	}
+
+	return first
+}
+
+// SetChangelogLink sets the link reference for the version in the changelog,
+// replacing the existing link reference if there is one. New link references
+// are inserted after any Unreleased link reference, before the first link
+// reference of an older version, or at the end. An Unreleased link reference
+// to the compare page from an older version to HEAD is changed to compare from
+// the version.
+func SetChangelogLink(changelog, version, url string) string {
+	r, _ := regexp.Compile(changelogLinkRegex)
+	unreleased, _ := regexp.Compile(changelogUnreleasedLinkRegex)
+
+	lines := strings.Split(strings.TrimRight(changelog, "\n"), "\n")
+	link := "[" + version + "]: " + url
+
+	insert := -1
+
+	for i, line := range lines {
+		if matches := unreleased.FindStringSubmatch(line); matches != nil && newerVersion(version, matches[2]) {
+			lines[i] = matches[1] + version + matches[3]
+			continue
+		}
+
+		matches := r.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		if sameVersion(matches[1], version) {
+			lines[i] = link
+			return strings.Join(lines, "\n") + "\n"
+		}
+
+		if insert == -1 && newerVersion(version, matches[1]) {
+			insert = i
+		}
+	}
+
+	if insert == -1 {
+		if last := lines[len(lines)-1]; !r.MatchString(last) && last != "" {
+			lines = append(lines, "")
+		}
+
+		lines = append(lines, link)
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	var output []string
+	output = append(output, lines[:insert]...)
+	output = append(output, link)
+	output = append(output, lines[insert:]...)
+
+	return strings.Join(output, "\n") + "\n"
+}
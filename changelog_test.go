@@ -0,0 +1,201 @@
+package releasekit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChangelog(t *testing.T) {
+	tests := []struct {
+		name      string
+		changelog string
+		want      []*ChangelogSection
+	}{
+		{
+			name:      "no sections",
+			changelog: "# Changelog\n\nAll notable changes.\n",
+		},
+		{
+			name: "sections",
+			changelog: "# Changelog\n\n" +
+				"## [Unreleased]\n\n- Next\n\n" +
+				"## [1.1.0] - 2020-02-01\n\n### Added\n\n- Second release\n\n" +
+				"## v1.0.0 - 2020-01-01\n\n- First release\n",
+			want: []*ChangelogSection{
+				{Version: "Unreleased", Body: "- Next"},
+				{Version: "1.1.0", Date: "2020-02-01", Body: "### Added\n\n- Second release"},
+				{Version: "v1.0.0", Date: "2020-01-01", Body: "- First release"},
+			},
+		},
+		{
+			name: "empty section",
+			changelog: "## [1.1.0] - 2020-02-01\n\n" +
+				"## [1.0.0] - 2020-01-01\n- First release\n",
+			want: []*ChangelogSection{
+				{Version: "1.1.0", Date: "2020-02-01"},
+				{Version: "1.0.0", Date: "2020-01-01", Body: "- First release"},
+			},
+		},
+		{
+			name: "link references",
+			changelog: "## [1.1.0] - 2020-02-01\n\n- Second release\n\n" +
+				"## [1.0.0] - 2020-01-01\n\n- First release\n\n" +
+				"[1.1.0]: https://github.com/o/r/compare/1.0.0...1.1.0\n" +
+				"[1.0.0]: https://github.com/o/r/releases/tag/1.0.0\n",
+			want: []*ChangelogSection{
+				{Version: "1.1.0", Date: "2020-02-01", Body: "- Second release"},
+				{Version: "1.0.0", Date: "2020-01-01", Body: "- First release"},
+			},
+		},
+		{
+			name:      "windows line endings",
+			changelog: "## [1.0.0] - 2020-01-01\r\n\r\n- First release\r\n- Docs\r\n",
+			want: []*ChangelogSection{
+				{Version: "1.0.0", Date: "2020-01-01", Body: "- First release\n- Docs"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseChangelog(tt.changelog); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChangelog() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertChangelogSection(t *testing.T) {
+	tests := []struct {
+		name      string
+		changelog string
+		version   string
+		section   string
+		want      string
+	}{
+		{
+			name:    "empty changelog",
+			version: "v1.0.0",
+			section: "## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			want:    DefaultChangelogHeader + "\n## [v1.0.0] - 2020-01-01\n\n- First release\n",
+		},
+		{
+			name:      "newer version",
+			changelog: "# Changelog\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			version:   "v1.1.0",
+			section:   "## [v1.1.0] - 2020-02-01\n\n- Second release\n",
+			want:      "# Changelog\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n",
+		},
+		{
+			name:      "older version",
+			changelog: "# Changelog\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v0.9.0] - 2019-12-01\n\n- Beta\n",
+			version:   "v1.0.0",
+			section:   "## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			want:      "# Changelog\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n\n## [v0.9.0] - 2019-12-01\n\n- Beta\n",
+		},
+		{
+			name:      "oldest version before links",
+			changelog: "# Changelog\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n[v1.1.0]: https://example.com/v1.1.0\n",
+			version:   "v1.0.0",
+			section:   "## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			want:      "# Changelog\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n\n[v1.1.0]: https://example.com/v1.1.0\n",
+		},
+		{
+			name:      "existing version is replaced",
+			changelog: "# Changelog\n\n## [1.1.0] - 2020-02-01\n\n- Old notes\n\n## [1.0.0] - 2020-01-01\n\n- First release\n",
+			version:   "v1.1.0",
+			section:   "## [v1.1.0] - 2020-02-02\n\n- New notes\n",
+			want:      "# Changelog\n\n## [v1.1.0] - 2020-02-02\n\n- New notes\n\n## [1.0.0] - 2020-01-01\n\n- First release\n",
+		},
+		{
+			name:      "unreleased entries are kept",
+			changelog: "# Changelog\n\n## [Unreleased]\n\n- Hand written note\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			version:   "v1.1.0",
+			section:   "## [v1.1.0] - 2020-02-01\n\n- Second release\n",
+			want:      "# Changelog\n\n## [Unreleased]\n\n- Hand written note\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v1.0.0] - 2020-01-01\n\n- First release\n",
+		},
+		{
+			name:      "unreleased entries are kept when replacing",
+			changelog: "# Changelog\n\n## [Unreleased]\n\n- Hand written note\n\n## [v1.1.0] - 2020-02-01\n\n- Old notes\n",
+			version:   "v1.1.0",
+			section:   "## [v1.1.0] - 2020-02-02\n\n- New notes\n",
+			want:      "# Changelog\n\n## [Unreleased]\n\n- Hand written note\n\n## [v1.1.0] - 2020-02-02\n\n- New notes\n",
+		},
+		{
+			name:      "unreleased entries are kept for older versions",
+			changelog: "# Changelog\n\n## [Unreleased]\n\n- Pending change\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n",
+			version:   "v1.0.1",
+			section:   "## [v1.0.1] - 2020-01-15\n\n- Patch release\n",
+			want:      "# Changelog\n\n## [Unreleased]\n\n- Pending change\n\n## [v1.1.0] - 2020-02-01\n\n- Second release\n\n## [v1.0.1] - 2020-01-15\n\n- Patch release\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InsertChangelogSection(tt.changelog, tt.version, tt.section); got != tt.want {
+				t.Errorf("InsertChangelogSection() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetChangelogLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		changelog string
+		version   string
+		url       string
+		want      string
+	}{
+		{
+			name:      "no links",
+			changelog: "## [v1.0.0] - 2020-01-01\n\n- First release\n",
+			version:   "v1.0.0",
+			url:       "https://example.com/v1.0.0",
+			want:      "## [v1.0.0] - 2020-01-01\n\n- First release\n\n[v1.0.0]: https://example.com/v1.0.0\n",
+		},
+		{
+			name:      "newer version",
+			changelog: "## [v1.1.0]\n\n## [v1.0.0]\n\n[v1.0.0]: https://example.com/v1.0.0\n",
+			version:   "v1.1.0",
+			url:       "https://example.com/v1.1.0",
+			want:      "## [v1.1.0]\n\n## [v1.0.0]\n\n[v1.1.0]: https://example.com/v1.1.0\n[v1.0.0]: https://example.com/v1.0.0\n",
+		},
+		{
+			name:      "older version",
+			changelog: "## [v1.1.0]\n\n## [v1.0.0]\n\n[v1.1.0]: https://example.com/v1.1.0\n",
+			version:   "v1.0.0",
+			url:       "https://example.com/v1.0.0",
+			want:      "## [v1.1.0]\n\n## [v1.0.0]\n\n[v1.1.0]: https://example.com/v1.1.0\n[v1.0.0]: https://example.com/v1.0.0\n",
+		},
+		{
+			name:      "existing link is replaced",
+			changelog: "## [v1.0.0]\n\n[v1.0.0]: https://example.com/old\n",
+			version:   "v1.0.0",
+			url:       "https://example.com/new",
+			want:      "## [v1.0.0]\n\n[v1.0.0]: https://example.com/new\n",
+		},
+		{
+			name:      "unreleased link is updated",
+			changelog: "## [Unreleased]\n\n## [v1.0.0]\n\n[Unreleased]: https://github.com/o/r/compare/v1.0.0...HEAD\n[v1.0.0]: https://github.com/o/r/releases/tag/v1.0.0\n",
+			version:   "v1.1.0",
+			url:       "https://github.com/o/r/compare/v1.0.0...v1.1.0",
+			want:      "## [Unreleased]\n\n## [v1.0.0]\n\n[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n[v1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0\n[v1.0.0]: https://github.com/o/r/releases/tag/v1.0.0\n",
+		},
+		{
+			name:      "unreleased link is kept for older versions",
+			changelog: "[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n[v1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0\n",
+			version:   "v1.0.1",
+			url:       "https://github.com/o/r/compare/v1.0.0...v1.0.1",
+			want:      "[Unreleased]: https://github.com/o/r/compare/v1.1.0...HEAD\n[v1.1.0]: https://github.com/o/r/compare/v1.0.0...v1.1.0\n[v1.0.1]: https://github.com/o/r/compare/v1.0.0...v1.0.1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SetChangelogLink(tt.changelog, tt.version, tt.url); got != tt.want {
+				t.Errorf("SetChangelogLink() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
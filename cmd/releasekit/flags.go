@@ -1,42 +1,138 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"regexp"
 
 	flags "github.com/jessevdk/go-flags"
+
+	"github.com/tombell/releasekit"
 )
 
 var options struct {
-	Token string `short:"t" long:"token" description:"GitHub API token" required:"true" value-name:"TOKEN"`
-	Owner string `short:"o" long:"owner" description:"GitHub repository owner" required:"true" value-name:"USER/ORG"`
-	Repo  string `short:"r" long:"repo" description:"GitHub repository name" required:"true" value-name:"REPO"`
+	Token string `short:"t" long:"token" description:"GitHub (or GitLab/Gitea) API token" required:"true" value-name:"TOKEN" no-ini:"true"`
+	Owner string `short:"o" long:"owner" description:"GitHub repository owner (or GitLab namespace)" value-name:"USER/ORG"`
+	Repo  string `short:"r" long:"repo" description:"GitHub repository name (or GitLab project)" value-name:"REPO"`
+
+	Provider string `long:"provider" description:"Forge hosting the repository" choice:"github" choice:"gitlab" choice:"gitea" default:"github"`
+	APIURL   string `long:"api-url" description:"API base URL for the provider, e.g. https://HOST/api/v3/ for GitHub Enterprise Server (default: https://gitlab.com/api/v4/ for GitLab, required for Gitea)" value-name:"URL"`
+
+	UploadURL string `long:"upload-url" description:"GitHub Enterprise Server uploads URL (default: worked out from the API base URL)" value-name:"URL"`
+	CABundle  string `long:"ca-bundle" description:"File path to PEM encoded CA certificates to trust" value-name:"FILE_PATH"`
+	Proxy     string `long:"proxy" description:"HTTP proxy URL (default: from HTTPS_PROXY environment variable)" value-name:"URL"`
+
+	Prev string `short:"p" long:"previous" description:"Previous release tag (default: greatest semantic version tag lower than the next tag)" value-name:"GIT_TAG"`
+	Next string `short:"n" long:"next" description:"Next release tag" value-name:"GIT_TAG"`
 
-	Prev string `short:"p" long:"previous" description:"Previous release tag" value-name:"GIT_TAG"`
-	Next string `short:"n" long:"next" description:"Next release tag" required:"true" value-name:"GIT_TAG"`
+	TagPrefix       string `long:"tag-prefix" description:"Only consider semantic version tags with the prefix" value-name:"PREFIX"`
+	SkipPrereleases bool   `long:"skip-prereleases" description:"Ignore prerelease tags when finding the previous release tag"`
 
-	Dry bool `long:"dry" description:"Outputs the release notes instead of creating or updating"`
+	ComputeNext  bool     `long:"compute-next" description:"Compute the next release version from the changes since the previous tag, instead of creating or updating a release"`
+	Target       string   `long:"target" description:"Branch to compute the next release version for, or to generate a dry run changelog for when the next tag does not exist yet (default: default branch)" value-name:"BRANCH"`
+	PrereleaseID string   `long:"prerelease-id" description:"Compute the next release version as a prerelease with the identifier, e.g. rc" value-name:"ID"`
+	MajorLabels  []string `long:"major-label" description:"Label requiring a major version increment (default: breaking, major)" value-name:"LABEL"`
+	MinorLabels  []string `long:"minor-label" description:"Label requiring a minor version increment (default: feature, enhancement, feat, minor)" value-name:"LABEL"`
+
+	Dry    bool   `long:"dry" description:"Outputs the release notes instead of creating or updating, still writing the --changelog file"`
+	Format string `long:"format" description:"Output format" choice:"markdown" choice:"json" default:"markdown"`
 
 	Draft      bool `long:"draft" description:"Mark release as draft"`
 	Prerelease bool `long:"prerelease" description:"Mark release as prerelease"`
 
+	Conventional bool `long:"conventional" description:"Generate notes from Conventional Commits instead of issues and pull requests"`
+	Explain      bool `long:"explain" description:"Output why each closed issue and pull request was included in or left out of the notes, instead of creating or updating a release"`
+	IssuesFirst  bool `long:"issues-first" description:"List issues closed by pull requests with the pull requests under them, instead of under the pull requests"`
+
 	Labels      []string `long:"label" description:"Label to include in notes, if PR/issue has the label" value-name:"LABEL"`
 	Attachments []string `long:"attachment" description:"File path to attach release asset" value-name:"FILE_PATH"`
 	Watched     []string `long:"watch" description:"File path to watch for changes" value-name:"FILE_PATH"`
 
+	ExcludeLabels  []string `long:"exclude-label" description:"Label of issues and pull requests to leave out of the notes" value-name:"LABEL"`
+	ExcludeAuthors []string `long:"exclude-author" description:"Author of issues and pull requests to leave out of the notes, e.g. dependabot" value-name:"USER"`
+	ExcludeTitles  []string `long:"exclude-title" description:"Regular expression matching titles of issues and pull requests to leave out of the notes" value-name:"REGEX"`
+	Include        []int    `long:"include" description:"Number of an issue or pull request to never leave out by the exclude rules" value-name:"NUMBER"`
+	Exclude        []int    `long:"exclude" description:"Number of an issue or pull request to always leave out of the notes" value-name:"NUMBER"`
+
+	Sections []string `long:"section" description:"Section to group items with any of the labels under, in order of priority" value-name:"LABEL[,LABEL...]=TITLE"`
+	Other    string   `long:"other-section" description:"Section title for items not matching any section" value-name:"TITLE" default:"Other"`
+
+	Template string `long:"template" description:"File path to a Go text/template for the release notes" value-name:"FILE_PATH"`
+
+	Changelog       string `long:"changelog" description:"File path to a Keep a Changelog formatted file to add the release notes to, which is written even with --dry" value-name:"FILE_PATH"`
+	CommitChangelog bool   `long:"commit-changelog" description:"Commit the updated changelog to the repository"`
+	ChangelogBranch string `long:"changelog-branch" description:"Branch to commit the updated changelog to (default: default branch)" value-name:"BRANCH"`
+
+	ImportChangelog string `long:"import-changelog" description:"File path to a Keep a Changelog formatted file to create or update releases from" value-name:"FILE_PATH"`
+
+	LocalRepo   string `long:"local-repo" description:"Read tags, commits and changed files from the local clone of the repository at the path" value-name:"DIR"`
+	CommitPulls bool   `long:"commit-pulls" description:"Look up the pull requests of commits without a pull request number using the API, when using --local-repo"`
+
+	GraphQL bool `long:"graphql" description:"Use the GitHub GraphQL API to fetch the issues and pull requests in the release"`
+
+	Config string `long:"config" description:"File path to an INI file of flag values, e.g. for the exclude rules (default: .releasekit.ini, if it exists)" value-name:"FILE_PATH" no-ini:"true"`
+
 	Verbose bool `short:"v" long:"verbose" description:"Verbose debug output"`
 }
 
+// defaultConfigPath is the INI file of flag values used if it exists and the
+// --config flag is not given.
+const defaultConfigPath = ".releasekit.ini"
+
+// formatJSON is the machine-readable output format.
+const formatJSON = "json"
+
+// The names of the providers other than GitHub.
+const (
+	providerGitLab = "gitlab"
+	providerGitea  = "gitea"
+)
+
 var (
-	verbose     bool
-	owner       string
-	repo        string
-	previous    string
-	next        string
-	draft       bool
-	prerelease  bool
-	labels      []string
-	attachments []string
-	watched     []string
+	verbose      bool
+	format       string
+	owner        string
+	repo         string
+	previous     string
+	next         string
+	draft        bool
+	prerelease   bool
+	conventional bool
+	issuesFirst  bool
+	explain      bool
+	labels       []string
+	attachments  []string
+	watched      []string
+
+	exclusions *releasekit.ExclusionRules
+
+	tagPrefix       string
+	skipPrereleases bool
+
+	computeNext  bool
+	target       string
+	prereleaseID string
+	majorLabels  []string
+	minorLabels  []string
+
+	sections     []string
+	otherSection string
+	templatePath string
+
+	changelogPath   string
+	commitChangelog bool
+	changelogBranch string
+
+	importChangelogPath string
+
+	providerName string
+	apiURL       string
+	uploadURL    string
+	caBundle     string
+	proxy        string
+	useGraphQL   bool
+	localRepo    string
+	commitPulls  bool
 )
 
 // parseFlags parses the command line flags.
@@ -51,7 +147,35 @@ func parseFlags() {
 		os.Exit(1)
 	}
 
+	if err := parseConfig(parser); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// the owner and repo can be given by the config, so are only checked for
+	// after reading it
+	if options.Owner == "" {
+		fmt.Fprintln(os.Stderr, "the required flag `-o, --owner' was not specified")
+		os.Exit(1)
+	}
+
+	if options.Repo == "" {
+		fmt.Fprintln(os.Stderr, "the required flag `-r, --repo' was not specified")
+		os.Exit(1)
+	}
+
+	if options.Next == "" && !options.ComputeNext && options.ImportChangelog == "" {
+		fmt.Fprintln(os.Stderr, "the required flag `-n, --next' was not specified")
+		os.Exit(1)
+	}
+
+	if options.Explain && options.Conventional {
+		fmt.Fprintln(os.Stderr, "the flag `--explain' cannot be used with `--conventional'")
+		os.Exit(1)
+	}
+
 	verbose = options.Verbose
+	format = options.Format
 
 	owner = options.Owner
 	repo = options.Repo
@@ -59,7 +183,83 @@ func parseFlags() {
 	next = options.Next
 	prerelease = options.Prerelease
 	draft = options.Draft
+	conventional = options.Conventional
+	issuesFirst = options.IssuesFirst
+	explain = options.Explain
 	labels = options.Labels
 	attachments = options.Attachments
 	watched = options.Watched
+
+	tagPrefix = options.TagPrefix
+	skipPrereleases = options.SkipPrereleases
+
+	computeNext = options.ComputeNext
+	target = options.Target
+	prereleaseID = options.PrereleaseID
+	majorLabels = options.MajorLabels
+	minorLabels = options.MinorLabels
+
+	sections = options.Sections
+	otherSection = options.Other
+	templatePath = options.Template
+
+	changelogPath = options.Changelog
+	commitChangelog = options.CommitChangelog
+	changelogBranch = options.ChangelogBranch
+
+	importChangelogPath = options.ImportChangelog
+
+	providerName = options.Provider
+	apiURL = options.APIURL
+	uploadURL = options.UploadURL
+	caBundle = options.CABundle
+	proxy = options.Proxy
+	useGraphQL = options.GraphQL
+	localRepo = options.LocalRepo
+	commitPulls = options.CommitPulls
+
+	exclusions = &releasekit.ExclusionRules{
+		Labels:  options.ExcludeLabels,
+		Authors: options.ExcludeAuthors,
+		Include: options.Include,
+		Exclude: options.Exclude,
+	}
+
+	for _, title := range options.ExcludeTitles {
+		r, err := regexp.Compile(title)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid `--exclude-title' %q: %s\n", title, err)
+			os.Exit(1)
+		}
+
+		exclusions.Titles = append(exclusions.Titles, r)
+	}
+
+	if len(majorLabels) == 0 {
+		majorLabels = releasekit.DefaultMajorLabels
+	}
+
+	if len(minorLabels) == 0 {
+		minorLabels = releasekit.DefaultMinorLabels
+	}
+}
+
+// parseConfig reads the flag values from the INI file given by the --config
+// flag, or the default config file if it exists. Flags given on the command
+// line take precedence over the file.
+func parseConfig(parser *flags.Parser) error {
+	path := options.Config
+
+	if path == "" {
+		if _, err := os.Stat(defaultConfigPath); err != nil {
+			return nil
+		}
+
+		path = defaultConfigPath
+	}
+
+	ini := flags.NewIniParser(parser)
+	ini.ParseAsDefaults = true
+
+	return ini.ParseFile(path)
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/google/go-github/v18/github"
+
+	"github.com/tombell/releasekit"
+)
+
+// importChangelog creates or updates a release for each version in the
+// changelog that has a tag in the repository, using the version's section as
+// the release body.
+func importChangelog(ctx context.Context, provider releasekit.Provider) {
+	data, err := ioutil.ReadFile(importChangelogPath)
+	exitIfError(err, "Could not read changelog")
+
+	for _, section := range releasekit.ParseChangelog(string(data)) {
+		if section.IsUnreleased() {
+			continue
+		}
+
+		printIfVerbose("Finding tag for version (%s)...\n", section.Version)
+		tag, err := findTagForVersion(ctx, provider, section.Version)
+		exitIfError(err, "Could not find tag for version")
+
+		if tag == "" {
+			fmt.Fprintf(status, "Skipping %s (tag not found)\n", section.Version)
+			continue
+		}
+
+		printIfVerbose("Checking for existing release for tag (%s)...\n", tag)
+		release, err := provider.GetReleaseByTag(ctx, tag)
+		if errors.Is(err, releasekit.ErrReleaseNotFound) {
+			release, err = nil, nil
+		}
+		exitIfError(err, "Could not check for existing release")
+
+		if release != nil && strings.TrimSpace(release.GetBody()) == section.Body {
+			fmt.Fprintf(status, "Release unchanged (%s)\n", tag)
+			continue
+		}
+
+		action := "Updating"
+
+		if release == nil {
+			action = "Creating"
+
+			v, err := releasekit.ParseVersionTag(tag, tagPrefix)
+			pre := prerelease || (err == nil && v.IsPrerelease())
+
+			release = &github.RepositoryRelease{
+				TagName:    &tag,
+				Name:       &tag,
+				Draft:      &draft,
+				Prerelease: &pre,
+			}
+		}
+
+		if options.Dry {
+			fmt.Fprintf(status, "%s release (%s) (dry run)\n", action, tag)
+			continue
+		}
+
+		fmt.Fprintf(status, "%s release (%s)...\n", action, tag)
+
+		release.Body = &section.Body
+
+		release, err = provider.CreateOrEditRelease(ctx, release)
+		exitIfError(err, "Could not create or update release")
+
+		fmt.Println(*release.HTMLURL)
+	}
+}
+
+// findTagForVersion finds the tag for the changelog version, trying the
+// version with the tag prefix, and with a "v" prefix if the version doesn't
+// have one. An empty tag is returned if none exist.
+func findTagForVersion(ctx context.Context, provider releasekit.Provider, version string) (string, error) {
+	tags := []string{tagPrefix + version}
+
+	if !strings.HasPrefix(version, "v") {
+		tags = append(tags, tagPrefix+"v"+version)
+	}
+
+	for _, tag := range tags {
+		ok, err := provider.TagExists(ctx, tag)
+		if err != nil {
+			return "", err
+		}
+
+		if ok {
+			return tag, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/v18/github"
+
+	"github.com/tombell/releasekit"
+)
+
+// fakeProvider is a provider with the tags and releases, that fails the test
+// if a release is created or edited.
+type fakeProvider struct {
+	releasekit.Provider
+
+	t        *testing.T
+	tags     map[string]bool
+	releases map[string]*github.RepositoryRelease
+}
+
+func (p *fakeProvider) TagExists(ctx context.Context, tag string) (bool, error) {
+	return p.tags[tag], nil
+}
+
+func (p *fakeProvider) GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	if release, ok := p.releases[tag]; ok {
+		return release, nil
+	}
+
+	return nil, &releasekit.Error{Kind: releasekit.ErrReleaseNotFound}
+}
+
+func (p *fakeProvider) CreateOrEditRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	p.t.Errorf("release %s created or edited", release.GetTagName())
+	return release, nil
+}
+
+func TestImportChangelogDryRun(t *testing.T) {
+	f, err := ioutil.TempFile("", "CHANGELOG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	changelog := "# Changelog\n\n" +
+		"## [Unreleased]\n\n- Next\n\n" +
+		"## [1.2.0] - 2020-03-01\n\n- Third release\n\n" +
+		"## [1.1.0] - 2020-02-01\n\n- Second release\n\n" +
+		"## [v1.0.0] - 2020-01-01\n\n- First release\n\n" +
+		"## [0.1.0] - 2019-12-01\n\n- Beta\n"
+
+	if _, err := f.WriteString(changelog); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	provider := &fakeProvider{
+		t:    t,
+		tags: map[string]bool{"v1.2.0": true, "1.1.0": true, "v1.0.0": true},
+		releases: map[string]*github.RepositoryRelease{
+			"1.1.0":  {TagName: github.String("1.1.0"), Body: github.String("- Second release\n")},
+			"v1.0.0": {TagName: github.String("v1.0.0"), Body: github.String("- Initial release")},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	importChangelogPath, tagPrefix, status, options.Dry = f.Name(), "", &buf, true
+	defer func() {
+		importChangelogPath, status, options.Dry = "", os.Stdout, false
+	}()
+
+	importChangelog(context.Background(), provider)
+
+	want := "Creating release (v1.2.0) (dry run)\n" +
+		"Release unchanged (1.1.0)\n" +
+		"Updating release (v1.0.0) (dry run)\n" +
+		"Skipping 0.1.0 (tag not found)\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/go-github/v18/github"
@@ -12,9 +21,13 @@ import (
 	"github.com/tombell/releasekit"
 )
 
+// status is where progress messages are written, which is stderr when the
+// output format is machine-readable so stdout only contains the output.
+var status io.Writer = os.Stdout
+
 // printVersion will print the version, and commit SHA for the build.
 func printVersion() {
-	fmt.Printf("releasekit %s (%s)\n", version, commit)
+	fmt.Fprintf(status, "releasekit %s (%s)\n", version, commit)
 }
 
 // printIfVerbose will print out the formatted string if the verbose flag is
@@ -24,156 +37,548 @@ func printIfVerbose(format string, a ...interface{}) {
 		return
 	}
 
-	fmt.Printf(format, a...)
+	fmt.Fprintf(status, format, a...)
+}
+
+// printJSON will print the value as indented JSON.
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	exitIfError(err, "Could not encode JSON output")
+
+	fmt.Println(string(data))
+}
+
+// contextWithSignals returns a context that is cancelled when an interrupt or
+// terminate signal is received, so any in-flight requests are aborted.
+func contextWithSignals() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(ch)
+	}()
+
+	return ctx, cancel
+}
+
+// exitCode returns the exit code for the error, which is distinct for each
+// kind of error returned by the library.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, releasekit.ErrTagNotFound):
+		return 3
+	case errors.Is(err, releasekit.ErrReleaseNotFound):
+		return 4
+	case errors.Is(err, releasekit.ErrRateLimited):
+		return 5
+	case errors.Is(err, releasekit.ErrAssetExists):
+		return 6
+	case errors.Is(err, releasekit.ErrPermissionDenied):
+		return 7
+	case errors.Is(err, releasekit.ErrComparisonTruncated):
+		return 8
+	case errors.Is(err, context.Canceled):
+		return 130
+	}
+
+	return 1
 }
 
-// exitIfError will fatal log if the error is not nil.
+// exitIfError will log the error and exit with the exit code for the error if
+// the error is not nil.
 func exitIfError(err error, msg string) {
 	if err == nil {
 		return
 	}
 
-	log.Fatal(fmt.Sprintf("%s:\n%s", msg, err))
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "Aborted")
+	} else {
+		log.Print(fmt.Sprintf("%s:\n%s", msg, err))
+	}
+
+	os.Exit(exitCode(err))
 }
 
-// generateReleaseBody generates the release body from the slice of issues.
-func generateReleaseBody(issues []*github.Issue, changed []string, compare string, labels []string) string {
-	if len(issues) == 0 {
-		return "New Release"
+// createProvider creates the provider for the repository, making requests
+// using the transport.
+func createProvider(transport http.RoundTripper) (releasekit.Provider, error) {
+	switch providerName {
+	case providerGitLab:
+		baseURL := apiURL
+		if baseURL == "" {
+			baseURL = releasekit.DefaultGitLabURL
+		}
+
+		return releasekit.NewGitLabProvider(baseURL, options.Token, &http.Client{Transport: transport}, owner, repo)
+	case providerGitea:
+		if apiURL == "" {
+			return nil, errors.New("the --api-url flag is required for Gitea")
+		}
+
+		return releasekit.NewGiteaProvider(apiURL, options.Token, &http.Client{Transport: transport}, owner, repo)
 	}
 
-	output := "## Changes\n"
+	client := releasekit.CreateGitHubClient(options.Token, transport)
 
-	for _, issue := range issues {
-		output += fmt.Sprintf("* [#%d](%s) - %v", *issue.Number, *issue.HTMLURL, *issue.Title)
+	if apiURL != "" {
+		var err error
+		client, err = releasekit.CreateEnterpriseClient(options.Token, transport, apiURL, uploadURL)
+		if errors.Is(err, releasekit.ErrUploadURLRequired) {
+			return nil, fmt.Errorf("%w, use the --upload-url flag to set it", err)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		var include []string
+	var source releasekit.Source
+	if useGraphQL {
+		source = releasekit.NewGraphQLSource(client, owner, repo)
+	}
+
+	return releasekit.NewGitHubProvider(client, owner, repo, source), nil
+}
 
-		for _, label := range labels {
-			if hasLabel(issue, label) {
-				include = append(include, fmt.Sprintf("**%s**", label))
+// fetchIssues fetches the closed issues and pull requests, and runs them
+// through the filter pipeline to filter out any that are not part of the
+// release. The issues and pull requests to nest are returned too, which are the
+// closed ones that are not excluded and the ones the filters added, along with
+// the explanation of the decisions of the filters.
+func fetchIssues(ctx context.Context, fc *releasekit.FilterContext) ([]*github.Issue, []*github.Issue, *releasekit.Explanation) {
+	printIfVerbose("Fetching closed issues...\n")
+	closed, err := fc.Source.FetchClosedIssuesSince(ctx, fc.Since)
+	exitIfError(err, "Could not fetch closed issues")
+
+	pipeline := releasekit.DefaultPipeline(fc, exclusions)
+
+	issues, decisions, err := pipeline.Run(ctx, fc, closed)
+	exitIfError(err, "Could not fetch pull requests")
+
+	if verbose {
+		dropped := make(map[string]int)
+
+		for _, d := range decisions {
+			if !d.Keep {
+				dropped[d.Filter]++
 			}
 		}
 
-		if len(include) > 0 {
-			output += fmt.Sprintf(" %s", strings.Join(include, ", "))
+		for _, f := range pipeline.Filters {
+			printIfVerbose("Filtered out %d issues and pull requests (%s)...\n", dropped[f.Name()], f.Name())
 		}
+	}
 
-		output += fmt.Sprintf(" (@%v)", *issue.User.Login)
-		output += "\n"
+	type key struct {
+		pull   bool
+		number int
 	}
 
-	if len(changed) > 0 {
-		output += "\n### Watched File Changes\n"
-		output += fmt.Sprintf("Changes: %s\n", compare)
+	nesting := releasekit.FilterExcluded(closed, exclusions)
+	fetched := make(map[key]bool)
+
+	for _, issue := range closed {
+		fetched[key{issue.IsPullRequest(), issue.GetNumber()}] = true
+	}
 
-		for _, file := range changed {
-			output += fmt.Sprintf("* %s\n", file)
+	for _, issue := range issues {
+		if !fetched[key{issue.IsPullRequest(), issue.GetNumber()}] {
+			nesting = append(nesting, issue)
 		}
 	}
 
-	return output
+	return issues, nesting, releasekit.Explain(closed, decisions)
 }
 
-func hasLabel(issue *github.Issue, label string) bool {
-	for _, l := range issue.Labels {
-		if *l.Name == label {
-			return true
+// printExplanation outputs why each issue and pull request was included in or
+// left out of the release.
+func printExplanation(explanation *releasekit.Explanation) {
+	if format == formatJSON {
+		printJSON(explanation)
+		return
+	}
+
+	for _, c := range explanation.Candidates {
+		kind := "issue"
+		if c.PullRequest {
+			kind = "pull request"
+		}
+
+		result := "excluded"
+		if c.Included {
+			result = "included"
+		}
+
+		fmt.Printf("#%d %s (%s) - %s\n", c.Number, c.Title, kind, result)
+
+		for _, d := range c.Decisions {
+			decision := "dropped"
+			if d.Keep {
+				decision = "kept"
+			}
+
+			fmt.Printf("  %s: %s, %s\n", d.Filter, decision, d.Reason)
 		}
 	}
+}
+
+// fetchReferencedIssues fetches the issues in other repositories closed by the
+// pull requests or commits in the release, if the provider can get them.
+func fetchReferencedIssues(ctx context.Context, provider releasekit.Provider, issues []*github.Issue, comparison *github.CommitsComparison) []*github.Issue {
+	getter, ok := provider.(releasekit.IssueGetter)
+	if !ok {
+		return nil
+	}
+
+	refs := releasekit.CrossRepositoryReferences(issues, comparison.Commits, owner, repo)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	printIfVerbose("Fetching %d issues closed in other repositories...\n", len(refs))
+	referenced, err := releasekit.FetchReferencedIssues(ctx, getter, refs)
+	exitIfError(err, "Could not fetch issues in other repositories")
+
+	// the numbers to include and exclude are for the repository
+	rules := *exclusions
+	rules.Include, rules.Exclude = nil, nil
 
-	return false
+	return releasekit.FilterExcluded(referenced, &rules)
+}
+
+// updateChangelog adds the release notes to the changelog file, and commits it
+// to the repository if enabled. The changelog is not committed on a dry run.
+func updateChangelog(ctx context.Context, provider releasekit.Provider, notes *releasekit.Notes) {
+	printIfVerbose("Generating changelog section...\n")
+	section, err := releasekit.RenderNotes(releasekit.DefaultChangelogTemplate, notes)
+	exitIfError(err, "Could not render changelog section")
+
+	data, err := ioutil.ReadFile(changelogPath)
+	if err != nil && !os.IsNotExist(err) {
+		exitIfError(err, "Could not read changelog")
+	}
+
+	changelog := releasekit.InsertChangelogSection(string(data), notes.Next, section)
+	changelog = releasekit.SetChangelogLink(changelog, notes.Next, notes.CompareURL)
+
+	fmt.Fprintf(status, "Updating changelog (%s)...\n", changelogPath)
+	err = ioutil.WriteFile(changelogPath, []byte(changelog), 0644)
+	exitIfError(err, "Could not write changelog")
+
+	if !commitChangelog {
+		return
+	}
+
+	path := filepath.ToSlash(filepath.Clean(changelogPath))
+	message := fmt.Sprintf("Update %s for %s", filepath.Base(path), notes.Next)
+
+	if options.Dry {
+		fmt.Fprintf(status, "Committing changelog (%s) (dry run)\n", path)
+		return
+	}
+
+	fmt.Fprintf(status, "Committing changelog (%s)...\n", path)
+	err = provider.CommitFile(ctx, path, changelogBranch, message, []byte(changelog))
+	exitIfError(err, "Could not commit changelog")
 }
 
 func main() {
-	printVersion()
 	parseFlags()
 
-	client := releasekit.CreateGitHubClient(options.Token)
+	if format == formatJSON {
+		status = os.Stderr
+	}
+
+	printVersion()
+
+	ctx, cancel := contextWithSignals()
+	defer cancel()
+
+	var rules []*releasekit.SectionRule
+
+	for _, section := range sections {
+		rule, err := releasekit.ParseSectionRule(section)
+		exitIfError(err, "Could not parse section")
+
+		rules = append(rules, rule)
+	}
+
+	httpTransport, err := releasekit.NewHTTPTransport(caBundle, proxy)
+	exitIfError(err, "Could not create HTTP transport")
+
+	transport := releasekit.NewRetryTransport(httpTransport)
+	transport.OnWait = func(wait time.Duration, reason string) {
+		printIfVerbose("Waiting %s to retry request (%s)...\n", wait.Round(time.Second), reason)
+	}
+
+	defer func() {
+		printIfVerbose("Made %d API requests\n", transport.Requests())
+	}()
+
+	provider, err := createProvider(transport)
+	exitIfError(err, "Could not create provider")
+
+	// the local clone doesn't know the pull requests of commits, so they are
+	// only looked up using the API if asked for
+	pulls := releasekit.NewPullRequestCache(provider)
+
+	if localRepo != "" {
+		printIfVerbose("Using local repository (%s)...\n", localRepo)
+		provider, err = releasekit.NewLocalProvider(ctx, localRepo, provider)
+		exitIfError(err, "Could not use local repository")
+
+		if !commitPulls {
+			pulls = releasekit.NewPullRequestCache(provider)
+		}
+	}
+
+	if importChangelogPath != "" {
+		importChangelog(ctx, provider)
+		return
+	}
+
+	ref := next
+
+	var versions []*releasekit.Version
+
+	if previous == "" || computeNext {
+		printIfVerbose("Fetching tags...\n")
+		tags, err := provider.ListTags(ctx)
+		exitIfError(err, "Could not fetch tags")
+
+		versions = releasekit.VersionTags(tags, tagPrefix)
+	}
+
+	if computeNext {
+		if previous == "" {
+			if latest := releasekit.LatestVersion(versions, false); latest != nil {
+				previous = latest.String()
+			}
+		}
+
+		if target == "" {
+			printIfVerbose("Fetching default branch...\n")
+			var err error
+			target, err = provider.GetDefaultBranch(ctx)
+			exitIfError(err, "Could not fetch default branch")
+		}
+
+		ref = target
+	} else if previous == "" {
+		if v, err := releasekit.ParseVersionTag(next, tagPrefix); err == nil {
+			if prev := releasekit.PreviousVersion(versions, v, !skipPrereleases); prev != nil {
+				previous = prev.String()
+				printIfVerbose("Using previous tag (%s)...\n", previous)
+			}
+		}
+	}
 
 	var since time.Time
+	var base *github.RepositoryCommit
 
 	if previous == "" || previous == next {
 		printIfVerbose("Fetching first commit...\n")
-		commit, err := releasekit.GetFirstCommit(client, owner, repo)
+		var err error
+		base, err = provider.GetFirstCommit(ctx)
 		exitIfError(err, "Could not fetch first commit")
 
-		sha := *commit.SHA
+		sha := *base.SHA
 		previous = sha[:8]
 	} else {
 		printIfVerbose("Fetching commit for tag (%s)...\n", previous)
-		base, err := releasekit.GetCommitForTag(client, owner, repo, previous)
+		var err error
+		base, err = provider.GetCommitForTag(ctx, previous)
 		exitIfError(err, "Could not fetch commit for tag")
 
 		since = base.Commit.Author.Date.Add(-24 * time.Hour)
 	}
 
-	printIfVerbose("Fetching closed issues...\n")
-	issues, err := releasekit.FetchClosedIssuesSince(client, owner, repo, since)
-	exitIfError(err, "Could not fetch closed issues")
+	var head *github.RepositoryCommit
 
-	printIfVerbose("Fetching commit for tag (%s)...\n", next)
-	head, err := releasekit.GetCommitForTag(client, owner, repo, next)
-	exitIfError(err, "Could not fetch commit for tag")
+	if computeNext {
+		printIfVerbose("Fetching commit for branch (%s)...\n", ref)
+		head, err = provider.GetCommit(ctx, ref)
+		exitIfError(err, "Could not fetch commit for branch")
+	} else {
+		printIfVerbose("Fetching commit for tag (%s)...\n", ref)
+		head, err = provider.GetCommitForTag(ctx, ref)
+
+		// the changelog can be written before tagging on a dry run, using the
+		// target branch in place of the tag
+		if errors.Is(err, releasekit.ErrTagNotFound) && options.Dry && changelogPath != "" {
+			if target == "" {
+				printIfVerbose("Fetching default branch...\n")
+				target, err = provider.GetDefaultBranch(ctx)
+				exitIfError(err, "Could not fetch default branch")
+			}
 
-	printIfVerbose("Fetching commit comparison (%s...%s)...\n", previous, next)
-	comparison, err := releasekit.GetComparison(client, owner, repo, previous, next)
-	exitIfError(err, "Could not fetch commit comparison")
+			fmt.Fprintf(status, "Tag (%s) not found, using branch (%s)...\n", next, target)
+			ref = target
+			head, err = provider.GetCommit(ctx, ref)
+		}
 
-	if !since.IsZero() {
-		printIfVerbose("Filtering out issues closed before %s...\n", since)
-		issues = releasekit.FilterClosedBefore(issues, since)
+		exitIfError(err, "Could not fetch commit for tag")
 	}
 
-	printIfVerbose("Filtering out issues closed after %s...\n", *head.Commit.Author.Date)
-	issues = releasekit.FilterClosedAfter(issues, *head.Commit.Author.Date)
+	printIfVerbose("Fetching commit comparison (%s...%s)...\n", previous, ref)
+	comparison, err := provider.GetComparison(ctx, previous, ref)
+	exitIfError(err, "Could not fetch commit comparison")
 
-	printIfVerbose("Filtering out issues closed by a pull request...\n")
-	issues = releasekit.FilterClosedByPull(issues)
+	items := []*releasekit.Item{}
 
-	printIfVerbose("Filtering out non-merged pull requests...\n")
-	issues = releasekit.FilterNonMergedPulls(issues, client, owner, repo)
+	if conventional {
+		printIfVerbose("Parsing conventional commits...\n")
+		commits := releasekit.FilterExcludedCommits(comparison.Commits, exclusions)
+		items = releasekit.ConventionalItems(commits, labels)
 
-	if len(comparison.Commits) > 0 {
-		printIfVerbose("Filtering out issues not closed by a commit...\n")
-		issues = releasekit.FilterClosedByCommits(issues, comparison.Commits)
+		if len(rules) == 0 {
+			rules = releasekit.DefaultConventionalSections
+		}
+	} else {
+		fc := &releasekit.FilterContext{
+			Source:  provider,
+			Pulls:   pulls,
+			Owner:   owner,
+			Repo:    repo,
+			Base:    base,
+			Head:    head,
+			Since:   since,
+			Commits: comparison.Commits,
+		}
+
+		issues, nesting, explanation := fetchIssues(ctx, fc)
+
+		if explain {
+			printExplanation(explanation)
+			return
+		}
+
+		referenced := fetchReferencedIssues(ctx, provider, issues, comparison)
+
+		for _, issue := range issues {
+			items = append(items, releasekit.NewItem(issue, labels))
+		}
+
+		for _, issue := range referenced {
+			item := releasekit.NewItem(issue, labels)
+			item.Repository = issue.GetRepository().GetFullName()
+			items = append(items, item)
+		}
 
-		printIfVerbose("Filtering out pull requests merged after tag (%s)...\n", next)
-		issues = releasekit.FilterMergedPullsAfter(issues, comparison.Commits)
+		printIfVerbose("Nesting issues under the pull requests that closed them...\n")
+		items = releasekit.NestClosedIssues(items, append(nesting, referenced...), labels, owner, repo)
+	}
+
+	if computeNext {
+		bump := releasekit.DetermineBump(items, majorLabels, minorLabels)
+		version := releasekit.NextVersion(versions, bump, prereleaseID)
+
+		if format == formatJSON {
+			printJSON(map[string]string{
+				"previous": previous,
+				"bump":     bump.String(),
+				"next":     version.String(),
+			})
+			return
+		}
+
+		fmt.Printf("previous=%s\n", previous)
+		fmt.Printf("bump=%s\n", bump)
+		fmt.Printf("next=%s\n", version)
+		return
 	}
 
 	var changed []string
 
 	if len(watched) > 0 {
 		printIfVerbose("Checking for changes in watched files...\n")
+		files, err := releasekit.ComparisonFiles(ctx, provider, comparison)
+		exitIfError(err, "Could not fetch changed files")
 
 		for _, file := range watched {
 			name := filepath.Clean(file)
 
-			for _, commitFile := range comparison.Files {
-				if name == *commitFile.Filename {
+			for _, f := range files {
+				if name == f {
 					changed = append(changed, name)
 				}
 			}
 		}
 	}
 
+	releasekit.AnnotateItems(items, comparison.Commits, owner, repo)
+
+	if issuesFirst {
+		items = releasekit.IssuesFirst(items)
+	}
+
+	compareURL := *comparison.HTMLURL
+	if ref != next && !computeNext {
+		// link to the compare page for the tag that is yet to be pushed
+		compareURL = strings.TrimSuffix(compareURL, ref) + next
+	}
+
+	notes := &releasekit.Notes{
+		Previous:       previous,
+		Next:           next,
+		PreviousCommit: base.GetSHA(),
+		NextCommit:     head.GetSHA(),
+		CompareURL:     compareURL,
+		Date:           head.GetCommit().GetAuthor().GetDate(),
+		Items:          items,
+		Labels:         labels,
+		Changed:        changed,
+	}
+
+	if len(rules) > 0 {
+		printIfVerbose("Grouping items into sections...\n")
+		notes.Sections = releasekit.GroupSections(notes.Items, rules, otherSection)
+	}
+
+	tmpl := releasekit.DefaultTemplate
+
+	if templatePath != "" {
+		printIfVerbose("Reading release notes template (%s)...\n", templatePath)
+		data, err := ioutil.ReadFile(templatePath)
+		exitIfError(err, "Could not read release notes template")
+
+		tmpl = string(data)
+	}
+
 	printIfVerbose("Generating release body...\n")
-	body := generateReleaseBody(issues, changed, *comparison.HTMLURL, labels)
+	body, err := releasekit.RenderNotes(tmpl, notes)
+	exitIfError(err, "Could not render release notes")
+
+	if changelogPath != "" {
+		updateChangelog(ctx, provider, notes)
+	}
 
 	if options.Dry {
+		if format == formatJSON {
+			printJSON(notes)
+			return
+		}
+
 		fmt.Println()
 		fmt.Println(body)
 		return
 	}
 
 	printIfVerbose("Checking for existing release for tag (%s)...\n", next)
-	release, err := releasekit.GetReleaseByTag(client, owner, repo, next)
-	exitIfError(err, "Could not check for existing release")
-
-	if release == nil {
-		release = &github.RepositoryRelease{}
+	release, err := provider.GetReleaseByTag(ctx, next)
+	if errors.Is(err, releasekit.ErrReleaseNotFound) {
+		release, err = &github.RepositoryRelease{}, nil
 	}
+	exitIfError(err, "Could not check for existing release")
 
 	release.TagName = &next
 	release.Name = &next
@@ -183,19 +588,25 @@ func main() {
 	release.Prerelease = &prerelease
 
 	if release.ID != nil {
-		fmt.Printf("Updating release (%s)...\n", *release.TagName)
+		fmt.Fprintf(status, "Updating release (%s)...\n", *release.TagName)
 	} else {
-		fmt.Printf("Creating release (%s)...\n", *release.TagName)
+		fmt.Fprintf(status, "Creating release (%s)...\n", *release.TagName)
 	}
 
-	release, err = releasekit.CreateOrEditRelease(client, owner, repo, release)
+	release, err = provider.CreateOrEditRelease(ctx, release)
 	exitIfError(err, "Could not create or update release")
 
 	if len(attachments) > 0 {
 		printIfVerbose("Uploading release assets...\n")
-		err = releasekit.UploadReleaseAssets(client, owner, repo, *release.ID, attachments)
+		err = provider.UploadReleaseAssets(ctx, release, attachments)
 		exitIfError(err, "Could not upload release assets")
 	}
 
+	if format == formatJSON {
+		notes.ReleaseURL = *release.HTMLURL
+		printJSON(notes)
+		return
+	}
+
 	fmt.Println(*release.HTMLURL)
 }
@@ -0,0 +1,40 @@
+package releasekit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// CommitFile creates or updates the file at the path in the repository with
+// the content, committing it to the branch with the message. If the branch is
+// empty the default branch is used.
+func CommitFile(ctx context.Context, c *github.Client, owner, repo, path, branch, message string, content []byte) error {
+	opt := &github.RepositoryContentFileOptions{
+		Message: &message,
+		Content: content,
+	}
+
+	var getOpt *github.RepositoryContentGetOptions
+
+	if branch != "" {
+		opt.Branch = &branch
+		getOpt = &github.RepositoryContentGetOptions{Ref: branch}
+	}
+
+	file, _, res, err := c.Repositories.GetContents(ctx, owner, repo, path, getOpt)
+	if err != nil && (res == nil || res.StatusCode != http.StatusNotFound) {
+		return wrapError(err, nil)
+	}
+
+	if file == nil {
+		_, _, err = c.Repositories.CreateFile(ctx, owner, repo, path, opt)
+		return wrapError(err, nil)
+	}
+
+	opt.SHA = file.SHA
+
+	_, _, err = c.Repositories.UpdateFile(ctx, owner, repo, path, opt)
+	return wrapError(err, nil)
+}
@@ -0,0 +1,243 @@
+package releasekit
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v18/github"
+)
+
+const (
+	conventionalHeaderRegex = `^(\w+)(?:\(([^()]*)\))?(!)?: (.+)$`
+	conventionalFooterRegex = `^(BREAKING CHANGE|BREAKING-CHANGE|[\w-]+)(?:: | #)(.*)$`
+	pullRequestSuffixRegex  = `\s*\(#([0-9]+)\)$`
+)
+
+// BreakingLabel is the label given to items built from conventional commits
+// with breaking changes.
+const BreakingLabel = "breaking"
+
+// DefaultConventionalSections are the section rules used for conventional
+// commits when no other section rules are given.
+var DefaultConventionalSections = []*SectionRule{
+	{Title: "Breaking Changes", Labels: []string{BreakingLabel}},
+	{Title: "Features", Labels: []string{"feat"}},
+	{Title: "Bug Fixes", Labels: []string{"fix"}},
+	{Title: "Performance Improvements", Labels: []string{"perf"}},
+	{Title: "Reverts", Labels: []string{"revert"}},
+	{Title: "Documentation", Labels: []string{"docs"}},
+}
+
+// ConventionalCommit is a commit message parsed using the Conventional Commits
+// specification.
+type ConventionalCommit struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+	Body        string
+	Footers     []*Footer
+	PullRequest int
+}
+
+// Footer is a single footer of a conventional commit message.
+type Footer struct {
+	Token string
+	Value string
+}
+
+// BreakingChange returns the description of the breaking change given in the
+// BREAKING CHANGE footer, if there is one.
+func (cc *ConventionalCommit) BreakingChange() string {
+	for _, footer := range cc.Footers {
+		if footer.Token == "BREAKING CHANGE" || footer.Token == "BREAKING-CHANGE" {
+			return footer.Value
+		}
+	}
+
+	return ""
+}
+
+// ParseConventionalCommit parses the commit message as a conventional commit,
+// returning false if the message does not follow the specification.
+func ParseConventionalCommit(message string) (*ConventionalCommit, bool) {
+	message = strings.TrimSpace(strings.Replace(message, "\r\n", "\n", -1))
+
+	paragraphs := strings.Split(message, "\n\n")
+
+	header, _ := regexp.Compile(conventionalHeaderRegex)
+
+	matches := header.FindStringSubmatch(strings.TrimSpace(paragraphs[0]))
+	if matches == nil {
+		return nil, false
+	}
+
+	cc := &ConventionalCommit{
+		Type:        strings.ToLower(matches[1]),
+		Scope:       strings.TrimSpace(matches[2]),
+		Breaking:    matches[3] == "!",
+		Description: matches[4],
+	}
+
+	suffix, _ := regexp.Compile(pullRequestSuffixRegex)
+	if matches := suffix.FindStringSubmatch(cc.Description); matches != nil {
+		cc.PullRequest, _ = strconv.Atoi(matches[1])
+		cc.Description = strings.TrimSuffix(cc.Description, matches[0])
+	}
+
+	body := paragraphs[1:]
+
+	if len(body) > 0 {
+		if footers := parseFooters(body[len(body)-1]); footers != nil {
+			cc.Footers = footers
+			body = body[:len(body)-1]
+		}
+	}
+
+	cc.Body = strings.TrimSpace(strings.Join(body, "\n\n"))
+
+	if cc.BreakingChange() != "" {
+		cc.Breaking = true
+	}
+
+	return cc, true
+}
+
+// parseFooters parses the paragraph as conventional commit footers, returning
+// nil if the paragraph does not start with a footer.
+func parseFooters(paragraph string) []*Footer {
+	r, _ := regexp.Compile(conventionalFooterRegex)
+
+	var footers []*Footer
+
+	for _, line := range strings.Split(paragraph, "\n") {
+		matches := r.FindStringSubmatch(line)
+
+		if matches == nil {
+			if len(footers) == 0 {
+				return nil
+			}
+
+			last := footers[len(footers)-1]
+			last.Value += "\n" + line
+
+			continue
+		}
+
+		footers = append(footers, &Footer{Token: matches[1], Value: matches[2]})
+	}
+
+	return footers
+}
+
+// ConventionalItems builds the release notes items from the commits that
+// follow the Conventional Commits specification, ignoring any that do not. The
+// items are labelled with their commit type (and BreakingLabel for breaking
+// changes), and grouped by scope. Merge commits are ignored, as the commits
+// of the merged branch are included in the comparison.
+func ConventionalItems(commits []github.RepositoryCommit, highlight []string) []*Item {
+	var items []*Item
+
+	for _, commit := range commits {
+		cc, ok := ParseConventionalCommit(commit.GetCommit().GetMessage())
+		if !ok {
+			continue
+		}
+
+		items = append(items, NewCommitItem(commit, cc, highlight))
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Scope < items[j].Scope
+	})
+
+	return items
+}
+
+// FilterExcludedCommits filters out the conventional commits excluded by the
+// rules. The title of a commit is the first line of its message, its labels
+// are its type (and BreakingLabel for breaking changes), its author is the
+// login or name of the author, and its number is the number of its pull
+// request. Commits that are not conventional commits are kept.
+func FilterExcludedCommits(commits []github.RepositoryCommit, rules *ExclusionRules) []github.RepositoryCommit {
+	var filtered []github.RepositoryCommit
+
+	for _, commit := range commits {
+		cc, ok := ParseConventionalCommit(commit.GetCommit().GetMessage())
+		if !ok {
+			filtered = append(filtered, commit)
+			continue
+		}
+
+		item := NewCommitItem(commit, cc, nil)
+		title := strings.SplitN(strings.TrimSpace(commit.GetCommit().GetMessage()), "\n", 2)[0]
+
+		issue := &github.Issue{
+			Number: github.Int(item.Number),
+			Title:  github.String(strings.TrimSpace(title)),
+			User:   &github.User{Login: github.String(item.Author)},
+		}
+
+		for _, label := range item.Labels {
+			issue.Labels = append(issue.Labels, github.Label{Name: github.String(label)})
+		}
+
+		if !rules.Excludes(issue) {
+			filtered = append(filtered, commit)
+		}
+	}
+
+	return filtered
+}
+
+// NewCommitItem creates a release notes item from the conventional commit,
+// highlighting any of the given labels the item has.
+func NewCommitItem(commit github.RepositoryCommit, cc *ConventionalCommit, highlight []string) *Item {
+	item := &Item{
+		Number:         cc.PullRequest,
+		Title:          cc.Description,
+		Author:         commit.GetAuthor().GetLogin(),
+		Labels:         []string{cc.Type},
+		PullRequest:    cc.PullRequest != 0,
+		SHA:            commit.GetSHA(),
+		CommitURL:      commit.GetHTMLURL(),
+		Type:           cc.Type,
+		Scope:          cc.Scope,
+		Breaking:       cc.Breaking,
+		BreakingChange: cc.BreakingChange(),
+	}
+
+	if item.Author == "" {
+		item.Author = commit.GetCommit().GetAuthor().GetName()
+	}
+
+	if cc.Breaking {
+		item.Labels = append(item.Labels, BreakingLabel)
+	}
+
+	if item.PullRequest {
+		item.URL = pullRequestURL(item.CommitURL, item.SHA, item.Number)
+	}
+
+	item.highlight(highlight)
+
+	return item
+}
+
+// pullRequestURL builds the URL of the pull request (or GitLab merge request)
+// with the given number from the URL of a commit in the same repository.
+func pullRequestURL(commitURL, sha string, number int) string {
+	base := strings.TrimSuffix(commitURL, "/commit/"+sha)
+	if base == commitURL {
+		return ""
+	}
+
+	// GitLab commit URLs are under /-/commit/
+	if strings.HasSuffix(base, "/-") {
+		return base + "/merge_requests/" + strconv.Itoa(number)
+	}
+
+	return base + "/pull/" + strconv.Itoa(number)
+}
@@ -0,0 +1,134 @@
+package releasekit
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-github/v18/github"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    *ConventionalCommit
+	}{
+		{
+			name:    "not conventional",
+			message: "Fix the crash on startup",
+		},
+		{
+			name:    "missing description",
+			message: "feat:",
+		},
+		{
+			name:    "type and description",
+			message: "fix: handle empty tags",
+			want:    &ConventionalCommit{Type: "fix", Description: "handle empty tags"},
+		},
+		{
+			name:    "type is lowercased",
+			message: "Feat: add endpoint",
+			want:    &ConventionalCommit{Type: "feat", Description: "add endpoint"},
+		},
+		{
+			name:    "scope",
+			message: "feat(api): add endpoint",
+			want:    &ConventionalCommit{Type: "feat", Scope: "api", Description: "add endpoint"},
+		},
+		{
+			name:    "breaking marker",
+			message: "feat(api)!: remove endpoint",
+			want:    &ConventionalCommit{Type: "feat", Scope: "api", Breaking: true, Description: "remove endpoint"},
+		},
+		{
+			name:    "pull request suffix",
+			message: "fix: handle empty tags (#123)",
+			want:    &ConventionalCommit{Type: "fix", Description: "handle empty tags", PullRequest: 123},
+		},
+		{
+			name:    "body",
+			message: "fix: handle empty tags\n\nTags without a name were\ncausing a crash.",
+			want:    &ConventionalCommit{Type: "fix", Description: "handle empty tags", Body: "Tags without a name were\ncausing a crash."},
+		},
+		{
+			name:    "breaking change footer",
+			message: "feat: move endpoint\n\nThe endpoint is now versioned.\n\nBREAKING CHANGE: the endpoint has moved\nReviewed-by: octocat\nRefs #4",
+			want: &ConventionalCommit{
+				Type:        "feat",
+				Breaking:    true,
+				Description: "move endpoint",
+				Body:        "The endpoint is now versioned.",
+				Footers: []*Footer{
+					{Token: "BREAKING CHANGE", Value: "the endpoint has moved"},
+					{Token: "Reviewed-by", Value: "octocat"},
+					{Token: "Refs", Value: "4"},
+				},
+			},
+		},
+		{
+			name:    "multiline footer",
+			message: "feat: move endpoint\n\nBREAKING-CHANGE: the endpoint has moved\nto /v2/endpoint",
+			want: &ConventionalCommit{
+				Type:        "feat",
+				Breaking:    true,
+				Description: "move endpoint",
+				Footers:     []*Footer{{Token: "BREAKING-CHANGE", Value: "the endpoint has moved\nto /v2/endpoint"}},
+			},
+		},
+		{
+			name:    "windows line endings",
+			message: "fix: handle empty tags\r\n\r\nSome detail.\r\n",
+			want:    &ConventionalCommit{Type: "fix", Description: "handle empty tags", Body: "Some detail."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseConventionalCommit(tt.message)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseConventionalCommit(%q) ok = %t, want %t", tt.message, ok, tt.want != nil)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseConventionalCommit(%q) = %+v, want %+v", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExcludedCommits(t *testing.T) {
+	commit := func(sha, message, login string) github.RepositoryCommit {
+		c := testCommit(sha, message)
+		c.Author = &github.User{Login: github.String(login)}
+		return c
+	}
+
+	commits := []github.RepositoryCommit{
+		commit("a", "feat: add endpoint (#1)", "octocat"),
+		commit("b", "chore: tidy up", "octocat"),
+		commit("c", "build(deps): bump go-github (#2)", "dependabot[bot]"),
+		commit("d", "fix: handle empty tags (#3)", "octocat"),
+		commit("e", "Not a conventional commit", "octocat"),
+		commit("f", "docs: explain flags (#4)", "octocat"),
+	}
+
+	rules := &ExclusionRules{
+		Labels:  []string{"chore"},
+		Authors: []string{"dependabot"},
+		Titles:  []*regexp.Regexp{regexp.MustCompile(`^docs`)},
+		Include: []int{4},
+		Exclude: []int{3},
+	}
+
+	var shas []string
+
+	for _, c := range FilterExcludedCommits(commits, rules) {
+		shas = append(shas, c.GetSHA())
+	}
+
+	if want := []string{"a", "e", "f"}; !reflect.DeepEqual(shas, want) {
+		t.Errorf("kept commits %v, want %v", shas, want)
+	}
+}
@@ -0,0 +1,96 @@
+package releasekit
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// The kinds of errors returned by the API, which can be checked for using
+// errors.Is.
+var (
+	ErrTagNotFound      = errors.New("tag not found")
+	ErrReleaseNotFound  = errors.New("release not found")
+	ErrIssueNotFound    = errors.New("issue not found")
+	ErrPullNotFound     = errors.New("pull request not found")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrAssetExists      = errors.New("release asset already exists")
+	ErrPermissionDenied = errors.New("permission denied")
+
+	ErrComparisonTruncated = errors.New("comparison truncated")
+)
+
+// ErrUploadURLRequired is returned when the upload URL of a GitHub Enterprise
+// Server can't be worked out from the API base URL, so must be given.
+var ErrUploadURLRequired = errors.New("upload URL required")
+
+// Error is an error returned by the API along with the kind of error. The
+// underlying error, such as a *github.ErrorResponse, can be inspected using
+// errors.As.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if the target is the kind of error.
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// wrapError wraps the error returned by the API with the kind of error, using
+// notFound as the kind for not found responses. Errors that are not one of the
+// kinds are returned as is.
+func wrapError(err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+
+	var rateLimit *github.RateLimitError
+	var abuseRateLimit *github.AbuseRateLimitError
+	var resp *github.ErrorResponse
+
+	var kind error
+
+	switch {
+	case errors.As(err, &rateLimit), errors.As(err, &abuseRateLimit):
+		kind = ErrRateLimited
+	case errors.As(err, &resp) && resp.Response != nil:
+		kind = kindForResponse(resp, notFound)
+	}
+
+	if kind == nil {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+func kindForResponse(resp *github.ErrorResponse, notFound error) error {
+	switch resp.Response.StatusCode {
+	case http.StatusNotFound:
+		return notFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusUnprocessableEntity:
+		for _, e := range resp.Errors {
+			if e.Code == "already_exists" {
+				return ErrAssetExists
+			}
+		}
+	}
+
+	return nil
+}
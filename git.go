@@ -2,13 +2,26 @@ package releasekit
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 
 	"github.com/google/go-github/v18/github"
 )
 
+const (
+	// MaxComparisonFiles is the greatest number of files the API returns in a
+	// commit comparison.
+	MaxComparisonFiles = 300
+
+	// comparisonPageSize is the number of commits fetched per page of a large
+	// commit comparison.
+	comparisonPageSize = 100
+)
+
 // GetCommitForTag gets the commit a tag is a reference to.
-func GetCommitForTag(c *github.Client, owner, repo, tag string) (*github.RepositoryCommit, error) {
-	ref, _, err := c.Git.GetRef(context.Background(), owner, repo, "refs/tags/"+tag)
+func GetCommitForTag(ctx context.Context, c *github.Client, owner, repo, tag string) (*github.RepositoryCommit, error) {
+	ref, err := getTagRef(ctx, c, owner, repo, tag)
 	if err != nil {
 		return nil, err
 	}
@@ -16,29 +29,45 @@ func GetCommitForTag(c *github.Client, owner, repo, tag string) (*github.Reposit
 	sha := *ref.Object.SHA
 
 	if *ref.Object.Type == "tag" {
-		tag, _, err := c.Git.GetTag(context.Background(), owner, repo, *ref.Object.SHA)
+		tag, _, err := c.Git.GetTag(ctx, owner, repo, *ref.Object.SHA)
 		if err != nil {
-			return nil, err
+			return nil, wrapError(err, ErrTagNotFound)
 		}
 
 		sha = *tag.Object.SHA
 	}
 
-	commit, _, err := c.Repositories.GetCommit(context.Background(), owner, repo, sha)
+	commit, _, err := c.Repositories.GetCommit(ctx, owner, repo, sha)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err, nil)
 	}
 
 	return commit, nil
 }
 
+// getTagRef gets the reference for the tag.
+func getTagRef(ctx context.Context, c *github.Client, owner, repo, tag string) (*github.Reference, error) {
+	ref, res, err := c.Git.GetRef(ctx, owner, repo, "refs/tags/"+tag)
+	if err != nil {
+		// a successful response with an error means only tags starting with the
+		// tag name were found
+		if res != nil && res.StatusCode == http.StatusOK {
+			return nil, &Error{Kind: ErrTagNotFound, Err: err}
+		}
+
+		return nil, wrapError(err, ErrTagNotFound)
+	}
+
+	return ref, nil
+}
+
 // GetFirstCommit gets the first commit to the repository.
-func GetFirstCommit(c *github.Client, owner, repo string) (*github.RepositoryCommit, error) {
+func GetFirstCommit(ctx context.Context, c *github.Client, owner, repo string) (*github.RepositoryCommit, error) {
 	opts := &github.CommitsListOptions{}
 
-	commits, resp, err := c.Repositories.ListCommits(context.Background(), owner, repo, opts)
+	commits, resp, err := c.Repositories.ListCommits(ctx, owner, repo, opts)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err, nil)
 	}
 
 	if resp.NextPage == 0 {
@@ -47,16 +76,151 @@ func GetFirstCommit(c *github.Client, owner, repo string) (*github.RepositoryCom
 
 	opts.Page = resp.LastPage
 
-	commits, _, err = c.Repositories.ListCommits(context.Background(), owner, repo, opts)
+	commits, _, err = c.Repositories.ListCommits(ctx, owner, repo, opts)
 	if err != nil {
-		return nil, err
+		return nil, wrapError(err, nil)
 	}
 
 	return commits[len(commits)-1], nil
 }
 
 // GetComparison gets the commit comparison for the given base and head range.
-func GetComparison(c *github.Client, owner, repo, base, head string) (*github.CommitsComparison, error) {
-	comparison, _, err := c.Repositories.CompareCommits(context.Background(), owner, repo, base, head)
-	return comparison, err
+// The API only returns 250 commits at a time, so larger comparisons are fetched
+// a page at a time. If all the commits cannot be fetched ErrComparisonTruncated
+// is returned. The files are only included in the first page, so are limited
+// to MaxComparisonFiles files.
+func GetComparison(ctx context.Context, c *github.Client, owner, repo, base, head string) (*github.CommitsComparison, error) {
+	comparison, _, err := c.Repositories.CompareCommits(ctx, owner, repo, base, head)
+	if err != nil {
+		return nil, wrapError(err, nil)
+	}
+
+	total := comparison.GetTotalCommits()
+	if len(comparison.Commits) >= total {
+		return comparison, nil
+	}
+
+	var commits []github.RepositoryCommit
+
+	for page := 1; len(commits) < total; page++ {
+		u := fmt.Sprintf("repos/%v/%v/compare/%v...%v?per_page=%d&page=%d", owner, repo, base, head, comparisonPageSize, page)
+
+		req, err := c.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var p github.CommitsComparison
+
+		if _, err := c.Do(ctx, req, &p); err != nil {
+			return nil, wrapError(err, nil)
+		}
+
+		if len(p.Commits) == 0 {
+			break
+		}
+
+		commits = append(commits, p.Commits...)
+	}
+
+	if len(commits) < total {
+		return nil, &Error{
+			Kind: ErrComparisonTruncated,
+			Err:  fmt.Errorf("only %d of the %d commits between %s and %s could be fetched", len(commits), total, base, head),
+		}
+	}
+
+	comparison.Commits = commits
+
+	return comparison, nil
+}
+
+// ComparisonFiles returns the names of the files changed in the comparison. If
+// the source is a FileLister, which the GitHub sources are, and the comparison
+// has MaxComparisonFiles files it may have been truncated, so the files changed
+// by each commit are listed instead, DefaultConcurrency commits at a time.
+// Otherwise the files in the comparison are used as they are.
+func ComparisonFiles(ctx context.Context, source Source, comparison *github.CommitsComparison) ([]string, error) {
+	var files []string
+
+	lister, ok := source.(FileLister)
+
+	if !ok || len(comparison.Files) < MaxComparisonFiles {
+		for _, file := range comparison.Files {
+			files = append(files, file.GetFilename())
+		}
+
+		return files, nil
+	}
+
+	commitFiles := make([][]string, len(comparison.Commits))
+
+	err := each(ctx, DefaultConcurrency, len(comparison.Commits), func(ctx context.Context, i int) error {
+		f, err := lister.ListCommitFiles(ctx, comparison.Commits[i].GetSHA())
+		if err != nil {
+			return err
+		}
+
+		commitFiles[i] = f
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	for _, f := range commitFiles {
+		for _, file := range f {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// ListCommitFiles lists the names of the files changed by the commit.
+func ListCommitFiles(ctx context.Context, c *github.Client, owner, repo, sha string) ([]string, error) {
+	commit, _, err := c.Repositories.GetCommit(ctx, owner, repo, sha)
+	if err != nil {
+		return nil, wrapError(err, nil)
+	}
+
+	var files []string
+
+	for _, file := range commit.Files {
+		files = append(files, file.GetFilename())
+	}
+
+	return files, nil
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+func GetCommit(ctx context.Context, c *github.Client, owner, repo, ref string) (*github.RepositoryCommit, error) {
+	commit, _, err := c.Repositories.GetCommit(ctx, owner, repo, ref)
+	return commit, wrapError(err, nil)
+}
+
+// GetDefaultBranch gets the name of the default branch of the repository.
+func GetDefaultBranch(ctx context.Context, c *github.Client, owner, repo string) (string, error) {
+	repository, _, err := c.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return "", wrapError(err, nil)
+	}
+
+	return repository.GetDefaultBranch(), nil
+}
+
+// TagExists checks if the tag exists in the repository.
+func TagExists(ctx context.Context, c *github.Client, owner, repo, tag string) (bool, error) {
+	_, err := getTagRef(ctx, c, owner, repo, tag)
+	if errors.Is(err, ErrTagNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
 }
@@ -0,0 +1,155 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v18/github"
+)
+
+func TestGetComparison(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[string]http.HandlerFunc
+		want   []string
+		err    error
+	}{
+		{
+			name: "single page",
+			routes: map[string]http.HandlerFunc{
+				"GET /api/v3/repos/o/r/compare/v1...v2": respond(`{"total_commits": 2, "commits": [{"sha": "c1"}, {"sha": "c2"}]}`),
+			},
+			want: []string{"c1", "c2"},
+		},
+		{
+			name: "pages",
+			routes: map[string]http.HandlerFunc{
+				"GET /api/v3/repos/o/r/compare/v1...v2":                     respond(`{"total_commits": 3, "commits": [{"sha": "c3"}]}`),
+				"GET /api/v3/repos/o/r/compare/v1...v2?per_page=100&page=1": respond(`{"total_commits": 3, "commits": [{"sha": "c1"}, {"sha": "c2"}]}`),
+				"GET /api/v3/repos/o/r/compare/v1...v2?per_page=100&page=2": respond(`{"total_commits": 3, "commits": [{"sha": "c3"}]}`),
+			},
+			want: []string{"c1", "c2", "c3"},
+		},
+		{
+			name: "truncated",
+			routes: map[string]http.HandlerFunc{
+				"GET /api/v3/repos/o/r/compare/v1...v2":                     respond(`{"total_commits": 3, "commits": [{"sha": "c3"}]}`),
+				"GET /api/v3/repos/o/r/compare/v1...v2?per_page=100&page=1": respond(`{"total_commits": 3, "commits": [{"sha": "c1"}, {"sha": "c2"}]}`),
+				"GET /api/v3/repos/o/r/compare/v1...v2?per_page=100&page=2": respond(`{"total_commits": 3, "commits": []}`),
+			},
+			err: ErrComparisonTruncated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.routes)
+			defer srv.Close()
+
+			p := newTestGitHubProvider(t, srv, false)
+
+			comparison, err := p.GetComparison(context.Background(), "v1", "v2")
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("got error %v, want %v", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var shas []string
+
+			for _, c := range comparison.Commits {
+				shas = append(shas, c.GetSHA())
+			}
+
+			if !reflect.DeepEqual(shas, tt.want) {
+				t.Errorf("commits = %v, want %v", shas, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparisonFiles(t *testing.T) {
+	files := func(names ...string) []github.CommitFile {
+		var f []github.CommitFile
+
+		for _, name := range names {
+			f = append(f, github.CommitFile{Filename: github.String(name)})
+		}
+
+		return f
+	}
+
+	// the comparison has as many files as GitHub returns, so they are listed for
+	// each commit instead
+	var truncated []string
+
+	for i := 0; i < MaxComparisonFiles; i++ {
+		truncated = append(truncated, fmt.Sprintf("file%d.go", i))
+	}
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v3/repos/o/r/commits/c1": respond(`{"sha": "c1", "files": [{"filename": "a.go"}, {"filename": "b.go"}]}`),
+		"GET /api/v3/repos/o/r/commits/c2": respond(`{"sha": "c2", "files": [{"filename": "b.go"}, {"filename": "c.go"}]}`),
+		"GET /api/v3/repos/o/r/commits/c3": respond(`{"sha": "c3", "files": [{"filename": "d.go"}]}`),
+	})
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		source     Source
+		comparison *github.CommitsComparison
+		want       []string
+	}{
+		{
+			name:   "files in the comparison",
+			source: newTestGitHubProvider(t, srv, false),
+			comparison: &github.CommitsComparison{
+				Commits: []github.RepositoryCommit{testCommit("c1", "First")},
+				Files:   files("a.go", "b.go"),
+			},
+			want: []string{"a.go", "b.go"},
+		},
+		{
+			name:   "files listed for each commit",
+			source: newTestGitHubProvider(t, srv, false),
+			comparison: &github.CommitsComparison{
+				Commits: []github.RepositoryCommit{testCommit("c1", "First"), testCommit("c2", "Second"), testCommit("c3", "Third")},
+				Files:   files(truncated...),
+			},
+			want: []string{"a.go", "b.go", "c.go", "d.go"},
+		},
+		{
+			name:   "source can't list files",
+			source: &fakeSource{},
+			comparison: &github.CommitsComparison{
+				Commits: []github.RepositoryCommit{testCommit("c1", "First")},
+				Files:   files(truncated...),
+			},
+			want: truncated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComparisonFiles(context.Background(), tt.source, tt.comparison)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %s, want %s", strings.Join(got, ", "), strings.Join(tt.want, ", "))
+			}
+		})
+	}
+}
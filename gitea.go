@@ -0,0 +1,464 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// GiteaProvider is a Provider for a repository on Gitea or Forgejo, using the
+// Gitea API. Most of the Gitea API responses have the same shape as the GitHub
+// API, so are decoded into the GitHub types.
+type GiteaProvider struct {
+	Owner string
+	Repo  string
+
+	api  *apiClient
+	repo string
+
+	mu      sync.Mutex
+	details *giteaRepository
+	pulls   map[int]*github.PullRequest
+}
+
+// NewGiteaProvider creates a new provider for the repository on the Gitea
+// instance with the API base URL, such as https://gitea.example.com/api/v1/,
+// using the token for authentication. If the HTTP client is nil,
+// http.DefaultClient is used.
+func NewGiteaProvider(baseURL, token string, client *http.Client, owner, repo string) (*GiteaProvider, error) {
+	header := http.Header{}
+	header.Set("Authorization", "token "+token)
+
+	api, err := newAPIClient(baseURL, client, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GiteaProvider{
+		Owner: owner,
+		Repo:  repo,
+		api:   api,
+		repo:  "repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo),
+		pulls: make(map[int]*github.PullRequest),
+	}, nil
+}
+
+// GetCommitForTag gets the commit a tag is a reference to.
+func (p *GiteaProvider) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	var t struct {
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+
+	if _, err := p.api.get(ctx, p.repo+"/tags/"+url.PathEscape(tag), &t, ErrTagNotFound); err != nil {
+		return nil, err
+	}
+
+	var commit github.RepositoryCommit
+
+	if _, err := p.api.get(ctx, p.repo+"/git/commits/"+t.Commit.SHA, &commit, nil); err != nil {
+		return nil, err
+	}
+
+	return &commit, nil
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+func (p *GiteaProvider) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+
+	query := url.Values{"sha": {ref}, "limit": {"1"}, "stat": {"false"}}
+
+	if _, err := p.api.get(ctx, p.repo+"/commits?"+query.Encode(), &commits, nil); err != nil {
+		return nil, err
+	}
+
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commit for %q", ref)
+	}
+
+	return commits[0], nil
+}
+
+// GetFirstCommit gets the first commit to the repository.
+func (p *GiteaProvider) GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error) {
+	path := p.repo + "/commits?limit=50&stat=false"
+
+	for {
+		var commits []*github.RepositoryCommit
+
+		resp, err := p.api.get(ctx, path, &commits, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip straight to the last page using the number of pages
+		if pages, err := strconv.Atoi(resp.Header.Get("X-PageCount")); err == nil && pages > 1 && resp.Request.URL.Query().Get("page") == "" {
+			path = fmt.Sprintf("%s/commits?limit=50&stat=false&page=%d", p.repo, pages)
+			continue
+		}
+
+		if next := nextPage(resp); next != "" {
+			path = next
+			continue
+		}
+
+		if len(commits) == 0 {
+			return nil, errors.New("repository has no commits")
+		}
+
+		return commits[len(commits)-1], nil
+	}
+}
+
+// GetComparison gets the commit comparison for the given base and head range.
+// The files changed are the files changed by each of the commits.
+func (p *GiteaProvider) GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error) {
+	var compare struct {
+		TotalCommits int                       `json:"total_commits"`
+		Commits      []github.RepositoryCommit `json:"commits"`
+	}
+
+	path := p.repo + "/compare/" + url.PathEscape(base) + "..." + url.PathEscape(head)
+
+	if _, err := p.api.get(ctx, path, &compare, nil); err != nil {
+		return nil, err
+	}
+
+	repository, err := p.getRepository(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &github.CommitsComparison{
+		TotalCommits: github.Int(compare.TotalCommits),
+		Commits:      compare.Commits,
+		HTMLURL:      github.String(repository.HTMLURL + "/compare/" + base + "..." + head),
+	}
+
+	seen := make(map[string]bool)
+
+	for _, commit := range compare.Commits {
+		for _, file := range commit.Files {
+			if !seen[file.GetFilename()] {
+				seen[file.GetFilename()] = true
+				comparison.Files = append(comparison.Files, file)
+			}
+		}
+	}
+
+	return comparison, nil
+}
+
+// FetchClosedIssuesSince fetches all closed issues and pull requests since the
+// specified time. The merged state of the pull requests is cached, so getting
+// them afterwards does not make any requests.
+func (p *GiteaProvider) FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error) {
+	query := url.Values{"state": {"closed"}, "limit": {"50"}}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+
+	var all []*github.Issue
+
+	for path := p.repo + "/issues?" + query.Encode(); path != ""; {
+		var issues []*giteaIssue
+
+		resp, err := p.api.get(ctx, path, &issues, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, issue := range issues {
+			all = append(all, issue.issue())
+
+			if issue.PullRequest != nil {
+				p.cache(issue.pullRequest())
+			}
+		}
+
+		path = nextPage(resp)
+	}
+
+	return all, nil
+}
+
+// GetPullRequest gets the pull request with the specified number, from the
+// pull requests already fetched if possible.
+func (p *GiteaProvider) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	p.mu.Lock()
+	pr, ok := p.pulls[number]
+	p.mu.Unlock()
+
+	if ok {
+		return pr, nil
+	}
+
+	pr = &github.PullRequest{}
+
+	if _, err := p.api.get(ctx, fmt.Sprintf("%s/pulls/%d", p.repo, number), pr, ErrPullNotFound); err != nil {
+		return nil, err
+	}
+
+	p.cache(pr)
+
+	return pr, nil
+}
+
+// GetIssue gets the issue with the specified number in the repository, which
+// can be any repository the token can access.
+func (p *GiteaProvider) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	var i giteaIssue
+
+	path := fmt.Sprintf("repos/%s/%s/issues/%d", url.PathEscape(owner), url.PathEscape(repo), number)
+
+	if _, err := p.api.get(ctx, path, &i, ErrIssueNotFound); err != nil {
+		return nil, err
+	}
+
+	return i.issue(), nil
+}
+
+func (p *GiteaProvider) cache(pr *github.PullRequest) {
+	p.mu.Lock()
+	p.pulls[pr.GetNumber()] = pr
+	p.mu.Unlock()
+}
+
+// ListTags lists the names of all the tags in the repository.
+func (p *GiteaProvider) ListTags(ctx context.Context) ([]string, error) {
+	var all []string
+
+	for path := p.repo + "/tags?limit=50"; path != ""; {
+		var tags []struct {
+			Name string `json:"name"`
+		}
+
+		resp, err := p.api.get(ctx, path, &tags, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			all = append(all, tag.Name)
+		}
+
+		path = nextPage(resp)
+	}
+
+	return all, nil
+}
+
+// TagExists checks if the tag exists in the repository.
+func (p *GiteaProvider) TagExists(ctx context.Context, tag string) (bool, error) {
+	_, err := p.api.get(ctx, p.repo+"/tags/"+url.PathEscape(tag), nil, ErrTagNotFound)
+	if errors.Is(err, ErrTagNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// GetDefaultBranch gets the name of the default branch of the repository.
+func (p *GiteaProvider) GetDefaultBranch(ctx context.Context) (string, error) {
+	repository, err := p.getRepository(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return repository.DefaultBranch, nil
+}
+
+// getRepository gets the details of the repository, which are only fetched
+// once.
+func (p *GiteaProvider) getRepository(ctx context.Context) (*giteaRepository, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.details != nil {
+		return p.details, nil
+	}
+
+	var repository giteaRepository
+
+	if _, err := p.api.get(ctx, p.repo, &repository, nil); err != nil {
+		return nil, err
+	}
+
+	p.details = &repository
+
+	return p.details, nil
+}
+
+// GetReleaseByTag returns the release for the given tag, or ErrReleaseNotFound
+// if it doesn't exist.
+func (p *GiteaProvider) GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	var release github.RepositoryRelease
+
+	if _, err := p.api.get(ctx, p.repo+"/releases/tags/"+url.PathEscape(tag), &release, ErrReleaseNotFound); err != nil {
+		return nil, err
+	}
+
+	return &release, nil
+}
+
+// CreateOrEditRelease creates the release if it doesn't have an ID, else it
+// edits the existing release.
+func (p *GiteaProvider) CreateOrEditRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	body := map[string]interface{}{
+		"tag_name":   release.GetTagName(),
+		"name":       release.GetName(),
+		"body":       release.GetBody(),
+		"draft":      release.GetDraft(),
+		"prerelease": release.GetPrerelease(),
+	}
+
+	var output github.RepositoryRelease
+	var err error
+
+	if release.ID == nil {
+		_, err = p.api.send(ctx, http.MethodPost, p.repo+"/releases", body, &output, ErrReleaseNotFound)
+	} else {
+		_, err = p.api.send(ctx, http.MethodPatch, fmt.Sprintf("%s/releases/%d", p.repo, release.GetID()), body, &output, ErrReleaseNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &output, nil
+}
+
+// UploadReleaseAssets uploads the files to the release as attachments.
+func (p *GiteaProvider) UploadReleaseAssets(ctx context.Context, release *github.RepositoryRelease, attachments []string) error {
+	for _, attachment := range attachments {
+		if err := p.uploadReleaseAsset(ctx, release, attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *GiteaProvider) uploadReleaseAsset(ctx context.Context, release *github.RepositoryRelease, attachment string) error {
+	f, err := os.OpenFile(attachment, os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := filepath.Clean(filepath.Base(f.Name()))
+
+	// Gitea allows attachments with the same name, so check for them to match
+	// GitHub
+	for _, asset := range release.Assets {
+		if asset.GetName() == name {
+			return &Error{Kind: ErrAssetExists, Err: fmt.Errorf("release %s already has an attachment named %q", release.GetTagName(), name)}
+		}
+	}
+
+	path := fmt.Sprintf("%s/releases/%d/assets?%s", p.repo, release.GetID(), url.Values{"name": {name}}.Encode())
+
+	_, err = p.api.upload(ctx, path, "attachment", name, f, nil, ErrReleaseNotFound)
+	return err
+}
+
+// CommitFile creates or updates the file at the path in the repository with
+// the content, committing it to the branch with the message. If the branch is
+// empty the default branch is used.
+func (p *GiteaProvider) CommitFile(ctx context.Context, path, branch, message string, content []byte) error {
+	file := p.repo + "/contents/" + escapePath(path)
+
+	body := map[string]interface{}{
+		"message": message,
+		"content": content,
+	}
+
+	query := url.Values{}
+
+	if branch != "" {
+		body["branch"] = branch
+		query.Set("ref", branch)
+	}
+
+	var existing struct {
+		SHA string `json:"sha"`
+	}
+
+	method := http.MethodPut
+
+	_, err := p.api.get(ctx, file+"?"+query.Encode(), &existing, errNotFound)
+	if errors.Is(err, errNotFound) {
+		method = http.MethodPost
+	} else if err != nil {
+		return err
+	} else {
+		body["sha"] = existing.SHA
+	}
+
+	_, err = p.api.send(ctx, method, file, body, nil, nil)
+	return err
+}
+
+type giteaRepository struct {
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// giteaIssue is an issue or pull request, which is the same as a GitHub issue
+// except for the pull request details.
+type giteaIssue struct {
+	github.Issue
+
+	PullRequest *struct {
+		Merged   bool       `json:"merged"`
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// issue converts the issue to the GitHub representation, with pull request
+// links for pull requests.
+func (i *giteaIssue) issue() *github.Issue {
+	issue := i.Issue
+
+	if i.PullRequest != nil {
+		issue.PullRequestLinks = &github.PullRequestLinks{HTMLURL: issue.HTMLURL}
+	}
+
+	return &issue
+}
+
+// pullRequest converts the pull request details of the issue to the GitHub
+// representation of a pull request.
+func (i *giteaIssue) pullRequest() *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:    i.Number,
+		State:     i.State,
+		Title:     i.Title,
+		Body:      i.Body,
+		HTMLURL:   i.HTMLURL,
+		User:      i.User,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+		Merged:    github.Bool(i.PullRequest.Merged),
+		MergedAt:  i.PullRequest.MergedAt,
+	}
+
+	for _, l := range i.Labels {
+		l := l
+		pr.Labels = append(pr.Labels, &l)
+	}
+
+	return pr
+}
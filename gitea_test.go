@@ -0,0 +1,235 @@
+package releasekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// newTestGiteaProvider creates a provider for the o/r repository on the
+// server.
+func newTestGiteaProvider(t *testing.T, srv *httptest.Server) *GiteaProvider {
+	p, err := NewGiteaProvider(srv.URL+"/api/v1/", "token", nil, "o", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestGiteaProviderGetCommitForTag(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/repos/o/r/tags/v1.0.0": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "token token" {
+				t.Errorf("Authorization = %q, want the token", got)
+			}
+
+			respond(`{"name": "v1.0.0", "commit": {"sha": "c1"}}`)(w, r)
+		},
+		"GET /api/v1/repos/o/r/git/commits/c1": respond(`{"sha": "c1", "commit": {"message": "Release"}}`),
+		"GET /api/v1/repos/o/r/tags/v2.0.0":    respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	ctx := context.Background()
+
+	commit, err := p.GetCommitForTag(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if commit.GetSHA() != "c1" || commit.GetCommit().GetMessage() != "Release" {
+		t.Errorf("commit = %v, want c1", commit)
+	}
+
+	if _, err := p.GetCommitForTag(ctx, "v2.0.0"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("got error %v, want ErrTagNotFound", err)
+	}
+
+	if exists, err := p.TagExists(ctx, "v2.0.0"); exists || err != nil {
+		t.Errorf("TagExists(v2.0.0) = %t, %v, want false", exists, err)
+	}
+}
+
+func TestGiteaProviderListTags(t *testing.T) {
+	var srv *httptest.Server
+
+	srv = newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/repos/o/r/tags?limit=50": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v1/repos/o/r/tags?limit=50&page=2>; rel="next"`, srv.URL))
+			respond(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`)(w, r)
+		},
+		"GET /api/v1/repos/o/r/tags?limit=50&page=2": respond(`[{"name": "v0.1.0"}]`),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	tags, err := p.ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"v1.1.0", "v1.0.0", "v0.1.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+}
+
+func TestGiteaProviderGetComparison(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/repos/o/r/compare/v1.0.0...v1.1.0": respond(`{
+			"total_commits": 2,
+			"commits": [
+				{"sha": "c1", "files": [{"filename": "README.md"}, {"filename": "main.go"}]},
+				{"sha": "c2", "files": [{"filename": "main.go"}]}
+			]
+		}`),
+		"GET /api/v1/repos/o/r": respond(`{"html_url": "https://gitea.example.com/o/r", "default_branch": "main"}`),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	comparison, err := p.GetComparison(context.Background(), "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if comparison.GetTotalCommits() != 2 || len(comparison.Commits) != 2 {
+		t.Errorf("commits = %v, want c1 and c2", comparison.Commits)
+	}
+
+	var files []string
+
+	for _, file := range comparison.Files {
+		files = append(files, file.GetFilename())
+	}
+
+	if want := []string{"README.md", "main.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+
+	if want := "https://gitea.example.com/o/r/compare/v1.0.0...v1.1.0"; comparison.GetHTMLURL() != want {
+		t.Errorf("URL = %s, want %s", comparison.GetHTMLURL(), want)
+	}
+}
+
+func TestGiteaProviderFetchClosedIssuesSince(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/repos/o/r/issues?limit=50&since=2020-01-01T00%3A00%3A00Z&state=closed": respond(`[
+			{"number": 2, "title": "Fix crash", "state": "closed", "labels": [{"name": "bug"}], "pull_request": {"merged": true, "merged_at": "2020-01-04T00:00:00Z"}},
+			{"number": 1, "title": "Crash", "state": "closed"}
+		]`),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	ctx := context.Background()
+
+	issues, err := p.FetchClosedIssuesSince(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(issues) != 2 || !issues[0].IsPullRequest() || issues[1].IsPullRequest() {
+		t.Fatalf("issues = %v, want the pull request then the issue", issues)
+	}
+
+	// the pull request is cached, so getting it doesn't make a request
+	pr, err := p.GetPullRequest(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pr.GetMerged() || pr.GetTitle() != "Fix crash" || len(pr.Labels) != 1 || pr.Labels[0].GetName() != "bug" {
+		t.Errorf("pull request = %v, want the merged #2", pr)
+	}
+}
+
+func TestGiteaProviderGetPullRequestNotFound(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v1/repos/o/r/pulls/1": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	if _, err := p.GetPullRequest(context.Background(), 1); !errors.Is(err, ErrPullNotFound) {
+		t.Errorf("got error %v, want ErrPullNotFound", err)
+	}
+}
+
+func TestGiteaProviderCreateOrEditRelease(t *testing.T) {
+	var bodies []map[string]interface{}
+
+	record := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var b map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				t.Error(err)
+			}
+
+			bodies = append(bodies, b)
+
+			respond(body)(w, r)
+		}
+	}
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/v1/repos/o/r/releases":            record(`{"id": 1, "tag_name": "v1.0.0", "body": "Notes"}`),
+		"PATCH /api/v1/repos/o/r/releases/1":         record(`{"id": 1, "tag_name": "v1.0.0", "body": "New notes"}`),
+		"GET /api/v1/repos/o/r/releases/tags/v2.0.0": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGiteaProvider(t, srv)
+
+	ctx := context.Background()
+
+	release, err := p.CreateOrEditRelease(ctx, &github.RepositoryRelease{
+		TagName: github.String("v1.0.0"),
+		Name:    github.String("v1.0.0"),
+		Body:    github.String("Notes"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release.Body = github.String("New notes")
+	release.Draft = github.Bool(true)
+
+	edited, err := p.CreateOrEditRelease(ctx, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.GetBody() != "New notes" {
+		t.Errorf("edited release = %v, want the new notes", edited)
+	}
+
+	want := []map[string]interface{}{
+		{"tag_name": "v1.0.0", "name": "v1.0.0", "body": "Notes", "draft": false, "prerelease": false},
+		{"tag_name": "v1.0.0", "name": "", "body": "New notes", "draft": true, "prerelease": false},
+	}
+
+	if !reflect.DeepEqual(bodies, want) {
+		t.Errorf("request bodies = %v, want %v", bodies, want)
+	}
+
+	if _, err := p.GetReleaseByTag(ctx, "v2.0.0"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("got error %v, want ErrReleaseNotFound", err)
+	}
+}
@@ -1,16 +1,45 @@
 package releasekit
 
 import (
-	"context"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/google/go-github/v18/github"
 	"golang.org/x/oauth2"
 )
 
 // CreateGitHubClient creates a new GitHub API client with the specified access
-// token for authentication.
-func CreateGitHubClient(token string) *github.Client {
+// token for authentication, making requests using the base transport. If the
+// base transport is nil, http.DefaultTransport is used.
+func CreateGitHubClient(token string, base http.RoundTripper) *github.Client {
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
+	tc := &http.Client{Transport: &oauth2.Transport{Source: ts, Base: base}}
 	return github.NewClient(tc)
 }
+
+// CreateEnterpriseClient creates a new GitHub Enterprise Server API client with
+// the specified access token for authentication, making requests using the base
+// transport. The API base URL is usually https://HOST/api/v3/, and if the
+// upload URL is empty it is worked out from the base URL. If the base URL
+// doesn't contain /api/v3 the upload URL can't be worked out, and an error
+// matching ErrUploadURLRequired is returned.
+func CreateEnterpriseClient(token string, base http.RoundTripper, baseURL, uploadURL string) (*github.Client, error) {
+	if uploadURL == "" {
+		if !strings.Contains(baseURL, "/api/v3") {
+			return nil, &Error{Kind: ErrUploadURLRequired, Err: fmt.Errorf("cannot work out the upload URL from %s, as it does not contain /api/v3", baseURL)}
+		}
+
+		uploadURL = strings.Replace(strings.TrimSuffix(baseURL, "/"), "/api/v3", "/api/uploads", 1)
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	tc := &http.Client{Transport: &oauth2.Transport{Source: ts, Base: base}}
+
+	c, err := github.NewEnterpriseClient(baseURL, uploadURL, tc)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,324 @@
+package releasekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newTestServer creates a server that handles requests using the handler for
+// the method and path, or the method and path with the query if there is one.
+// Other requests fail the test.
+func newTestServer(t *testing.T, routes map[string]http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, key := range []string{r.Method + " " + r.URL.RequestURI(), r.Method + " " + r.URL.EscapedPath()} {
+			if handler, ok := routes[key]; ok {
+				handler(w, r)
+				return
+			}
+		}
+
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+		http.NotFound(w, r)
+	}))
+}
+
+// respond returns a handler that responds with the JSON body.
+func respond(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}
+}
+
+// respondStatus returns a handler that responds with the status code and an
+// error message.
+func respondStatus(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"message": %q}`, http.StatusText(code))
+	}
+}
+
+func TestCreateEnterpriseClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseURL   string
+		uploadURL string
+		want      string
+		err       bool
+	}{
+		{
+			name:    "upload URL worked out",
+			baseURL: "https://ghe.example.com/api/v3/",
+			want:    "https://ghe.example.com/api/uploads/",
+		},
+		{
+			name:    "upload URL worked out without trailing slash",
+			baseURL: "https://ghe.example.com/api/v3",
+			want:    "https://ghe.example.com/api/uploads/",
+		},
+		{
+			name:      "upload URL given",
+			baseURL:   "https://api.ghe.example.com/",
+			uploadURL: "https://uploads.ghe.example.com",
+			want:      "https://uploads.ghe.example.com/",
+		},
+		{
+			name:    "upload URL can't be worked out",
+			baseURL: "https://api.ghe.example.com/",
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := CreateEnterpriseClient("token", nil, tt.baseURL, tt.uploadURL)
+			if tt.err {
+				if !errors.Is(err, ErrUploadURLRequired) {
+					t.Errorf("got error %v, want ErrUploadURLRequired", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if c.UploadURL.String() != tt.want {
+				t.Errorf("upload URL = %s, want %s", c.UploadURL, tt.want)
+			}
+		})
+	}
+}
+
+// newTestGitHubProvider creates a provider for the o/r repository on the
+// server, using the GraphQL API if graphQL is true.
+func newTestGitHubProvider(t *testing.T, srv *httptest.Server, graphQL bool) *GitHubProvider {
+	c, err := CreateEnterpriseClient("token", nil, srv.URL+"/api/v3/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var source Source
+	if graphQL {
+		source = NewGraphQLSource(c, "o", "r")
+	}
+
+	return NewGitHubProvider(c, "o", "r", source)
+}
+
+func TestGitHubProviderGetCommitForTag(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v3/repos/o/r/git/refs/tags/v1.0.0": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer token" {
+				t.Errorf("Authorization = %q, want the token", got)
+			}
+
+			respond(`{"ref": "refs/tags/v1.0.0", "object": {"sha": "t1", "type": "tag"}}`)(w, r)
+		},
+		"GET /api/v3/repos/o/r/git/tags/t1":          respond(`{"sha": "t1", "object": {"sha": "c1", "type": "commit"}}`),
+		"GET /api/v3/repos/o/r/git/refs/tags/v1.1.0": respond(`{"ref": "refs/tags/v1.1.0", "object": {"sha": "c2", "type": "commit"}}`),
+		"GET /api/v3/repos/o/r/git/refs/tags/v2.0.0": respondStatus(http.StatusNotFound),
+		"GET /api/v3/repos/o/r/commits/c1":           respond(`{"sha": "c1", "commit": {"message": "Annotated"}}`),
+		"GET /api/v3/repos/o/r/commits/c2":           respond(`{"sha": "c2", "commit": {"message": "Lightweight"}}`),
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, false)
+
+	ctx := context.Background()
+
+	for tag, want := range map[string]string{"v1.0.0": "c1", "v1.1.0": "c2"} {
+		commit, err := p.GetCommitForTag(ctx, tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if commit.GetSHA() != want {
+			t.Errorf("commit for %s = %s, want %s", tag, commit.GetSHA(), want)
+		}
+	}
+
+	if _, err := p.GetCommitForTag(ctx, "v2.0.0"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("got error %v, want ErrTagNotFound", err)
+	}
+}
+
+func TestGitHubProviderListTags(t *testing.T) {
+	var srv *httptest.Server
+
+	srv = newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v3/repos/o/r/tags?per_page=100": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/repos/o/r/tags?page=2&per_page=100>; rel="next"`, srv.URL))
+			respond(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`)(w, r)
+		},
+		"GET /api/v3/repos/o/r/tags?page=2&per_page=100": respond(`[{"name": "v0.1.0"}]`),
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, false)
+
+	tags, err := p.ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"v1.1.0", "v1.0.0", "v0.1.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+}
+
+func TestGitHubProviderListPullRequestsForCommits(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v3/repos/o/r/commits/c1/pulls": respond(`[
+			{"number": 1, "merged_at": "2020-01-01T00:00:00Z", "merge_commit_sha": "c1", "base": {"ref": "master"}},
+			{"number": 2, "merged_at": null}
+		]`),
+		"GET /api/v3/repos/o/r/commits/c2/pulls": respond(`[]`),
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, false)
+
+	pulls, err := p.ListPullRequestsForCommits(context.Background(), []string{"c1", "c2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pulls["c1"]) != 1 || pulls["c1"][0].GetNumber() != 1 || !pulls["c1"][0].GetMerged() {
+		t.Errorf("pull requests for c1 = %v, want only the merged #1", pulls["c1"])
+	}
+
+	if len(pulls["c2"]) != 0 {
+		t.Errorf("pull requests for c2 = %v, want none", pulls["c2"])
+	}
+}
+
+func TestGitHubProviderGetPullRequestNotFound(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v3/repos/o/r/pulls/1": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, false)
+
+	if _, err := p.GetPullRequest(context.Background(), 1); !errors.Is(err, ErrPullNotFound) {
+		t.Errorf("got error %v, want ErrPullNotFound", err)
+	}
+}
+
+// graphQLRequest is the body of a GraphQL API request.
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func TestGraphQLSourceListTags(t *testing.T) {
+	var cursors []interface{}
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/graphql": func(w http.ResponseWriter, r *http.Request) {
+			var req graphQLRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+
+			cursors = append(cursors, req.Variables["cursor"])
+
+			if req.Variables["cursor"] == nil {
+				respond(`{"data": {"repository": {"refs": {"pageInfo": {"hasNextPage": true, "endCursor": "next"}, "nodes": [{"name": "v1.0.0"}]}}}}`)(w, r)
+			} else {
+				respond(`{"data": {"repository": {"refs": {"pageInfo": {"hasNextPage": false}, "nodes": [{"name": "v1.1.0"}]}}}}`)(w, r)
+			}
+		},
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, true)
+
+	tags, err := p.ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"v1.0.0", "v1.1.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	if want := []interface{}{nil, "next"}; !reflect.DeepEqual(cursors, want) {
+		t.Errorf("cursors = %v, want %v", cursors, want)
+	}
+}
+
+func TestGraphQLSourceListPullRequestsForCommits(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/graphql": func(w http.ResponseWriter, r *http.Request) {
+			var req graphQLRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error(err)
+			}
+
+			if req.Variables["c0"] != "c1" || req.Variables["c1"] != "c2" {
+				t.Errorf("variables = %v, want the commits", req.Variables)
+			}
+
+			respond(`{"data": {"repository": {
+				"c0": {"associatedPullRequests": {"nodes": [
+					{
+						"number": 1,
+						"body": "Fixes #10",
+						"merged": true,
+						"mergeCommit": {"oid": "c1"},
+						"baseRefName": "master",
+						"closingIssuesReferences": {"nodes": [
+							{"number": 10, "repository": {"name": "r", "owner": {"login": "o"}}},
+							{"number": 11, "repository": {"name": "r", "owner": {"login": "o"}}},
+							{"number": 12, "repository": {"name": "other", "owner": {"login": "o"}}}
+						]}
+					},
+					{"number": 2, "merged": false}
+				]}},
+				"c1": null
+			}}}`)(w, r)
+		},
+	})
+	defer srv.Close()
+
+	p := newTestGitHubProvider(t, srv, true)
+
+	pulls, err := p.ListPullRequestsForCommits(context.Background(), []string{"c1", "c2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pulls["c1"]) != 1 {
+		t.Fatalf("pull requests for c1 = %v, want only the merged #1", pulls["c1"])
+	}
+
+	pr := pulls["c1"][0]
+
+	if pr.GetNumber() != 1 || pr.GetMergeCommitSHA() != "c1" || pr.GetBase().GetRef() != "master" {
+		t.Errorf("pull request = %v, want #1 merged into master by c1", pr)
+	}
+
+	if want := "Fixes #10\n\nCloses #11\n\nCloses o/other#12"; pr.GetBody() != want {
+		t.Errorf("body = %q, want %q", pr.GetBody(), want)
+	}
+
+	if len(pulls["c2"]) != 0 {
+		t.Errorf("pull requests for c2 = %v, want none", pulls["c2"])
+	}
+
+	// the pull request is cached, so getting it doesn't make a request
+	if cached, err := p.GetPullRequest(context.Background(), 1); err != nil || cached != pr {
+		t.Errorf("GetPullRequest(1) = %v, %v, want the cached pull request", cached, err)
+	}
+}
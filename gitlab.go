@@ -0,0 +1,614 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// DefaultGitLabURL is the URL of the GitLab.com API.
+const DefaultGitLabURL = "https://gitlab.com/api/v4/"
+
+// errNotFound is the kind of error for not found responses that are expected.
+var errNotFound = errors.New("not found")
+
+// GitLabProvider is a Provider for a project on GitLab, using the GitLab REST
+// API. Merge requests are represented as pull requests, and the links of a
+// release as its assets. GitLab releases cannot be drafts or prereleases, so
+// those fields are ignored.
+type GitLabProvider struct {
+	Owner string
+	Repo  string
+
+	api     *apiClient
+	project string
+
+	mu      sync.Mutex
+	details *gitLabProject
+	merges  map[int]*github.PullRequest
+}
+
+// NewGitLabProvider creates a new provider for the project on the GitLab
+// instance with the API base URL, such as DefaultGitLabURL, using the token
+// for authentication. The owner is the namespace of the project, which can
+// include subgroups. If the HTTP client is nil, http.DefaultClient is used.
+func NewGitLabProvider(baseURL, token string, client *http.Client, owner, repo string) (*GitLabProvider, error) {
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+
+	api, err := newAPIClient(baseURL, client, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitLabProvider{
+		Owner:   owner,
+		Repo:    repo,
+		api:     api,
+		project: "projects/" + url.PathEscape(owner+"/"+repo),
+		merges:  make(map[int]*github.PullRequest),
+	}, nil
+}
+
+// GetCommitForTag gets the commit a tag is a reference to.
+func (p *GitLabProvider) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	var t struct {
+		Commit gitLabCommit `json:"commit"`
+	}
+
+	if _, err := p.api.get(ctx, p.project+"/repository/tags/"+url.PathEscape(tag), &t, ErrTagNotFound); err != nil {
+		return nil, err
+	}
+
+	return t.Commit.repositoryCommit(), nil
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+func (p *GitLabProvider) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	var commit gitLabCommit
+
+	if _, err := p.api.get(ctx, p.project+"/repository/commits/"+url.PathEscape(ref), &commit, nil); err != nil {
+		return nil, err
+	}
+
+	return commit.repositoryCommit(), nil
+}
+
+// GetFirstCommit gets the first commit to the project.
+func (p *GitLabProvider) GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error) {
+	path := p.project + "/repository/commits?per_page=100"
+
+	for {
+		var commits []gitLabCommit
+
+		resp, err := p.api.get(ctx, path, &commits, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// skip straight to the last page if GitLab gives the number of pages,
+		// which it doesn't for very large projects
+		if pages, err := strconv.Atoi(resp.Header.Get("X-Total-Pages")); err == nil && pages > 1 && resp.Header.Get("X-Page") != strconv.Itoa(pages) {
+			path = fmt.Sprintf("%s/repository/commits?per_page=100&page=%d", p.project, pages)
+			continue
+		}
+
+		if next := nextPage(resp); next != "" {
+			path = next
+			continue
+		}
+
+		if len(commits) == 0 {
+			return nil, errors.New("project has no commits")
+		}
+
+		return commits[len(commits)-1].repositoryCommit(), nil
+	}
+}
+
+// GetComparison gets the commit comparison for the given base and head range.
+func (p *GitLabProvider) GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error) {
+	var compare struct {
+		Commits []gitLabCommit `json:"commits"`
+		Diffs   []struct {
+			NewPath string `json:"new_path"`
+		} `json:"diffs"`
+		WebURL string `json:"web_url"`
+	}
+
+	query := url.Values{"from": {base}, "to": {head}}
+
+	if _, err := p.api.get(ctx, p.project+"/repository/compare?"+query.Encode(), &compare, nil); err != nil {
+		return nil, err
+	}
+
+	if compare.WebURL == "" {
+		project, err := p.getProject(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		compare.WebURL = project.WebURL + "/-/compare/" + base + "..." + head
+	}
+
+	comparison := &github.CommitsComparison{
+		TotalCommits: github.Int(len(compare.Commits)),
+		HTMLURL:      github.String(compare.WebURL),
+	}
+
+	for _, commit := range compare.Commits {
+		comparison.Commits = append(comparison.Commits, *commit.repositoryCommit())
+	}
+
+	for _, diff := range compare.Diffs {
+		comparison.Files = append(comparison.Files, github.CommitFile{Filename: github.String(diff.NewPath)})
+	}
+
+	return comparison, nil
+}
+
+// FetchClosedIssuesSince fetches all closed issues and merged merge requests
+// since the specified time, newest first. The merge requests are cached, so
+// getting them afterwards does not make any requests.
+func (p *GitLabProvider) FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error) {
+	query := url.Values{"per_page": {"100"}}
+	if !since.IsZero() {
+		query.Set("updated_after", since.Format(time.RFC3339))
+	}
+
+	var all []*github.Issue
+
+	query.Set("state", "closed")
+
+	issues, err := p.listIssues(ctx, p.project+"/issues?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		all = append(all, issue.issue(false))
+	}
+
+	query.Set("state", "merged")
+
+	merges, err := p.listIssues(ctx, p.project+"/merge_requests?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mr := range merges {
+		p.cache(mr.pullRequest())
+		all = append(all, mr.issue(true))
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].GetCreatedAt().After(all[j].GetCreatedAt())
+	})
+
+	return all, nil
+}
+
+// listIssues lists all the pages of issues or merge requests.
+func (p *GitLabProvider) listIssues(ctx context.Context, path string) ([]*gitLabIssue, error) {
+	var all []*gitLabIssue
+
+	for path != "" {
+		var issues []*gitLabIssue
+
+		resp, err := p.api.get(ctx, path, &issues, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, issues...)
+		path = nextPage(resp)
+	}
+
+	return all, nil
+}
+
+// GetPullRequest gets the merge request with the specified number, from the
+// merge requests already fetched if possible.
+func (p *GitLabProvider) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	p.mu.Lock()
+	pr, ok := p.merges[number]
+	p.mu.Unlock()
+
+	if ok {
+		return pr, nil
+	}
+
+	var mr gitLabIssue
+
+	if _, err := p.api.get(ctx, fmt.Sprintf("%s/merge_requests/%d", p.project, number), &mr, ErrPullNotFound); err != nil {
+		return nil, err
+	}
+
+	pr = mr.pullRequest()
+	p.cache(pr)
+
+	return pr, nil
+}
+
+// GetIssue gets the issue with the specified number in the project, which can
+// be any project the token can access.
+func (p *GitLabProvider) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	var i gitLabIssue
+
+	path := fmt.Sprintf("projects/%s/issues/%d", url.PathEscape(owner+"/"+repo), number)
+
+	if _, err := p.api.get(ctx, path, &i, ErrIssueNotFound); err != nil {
+		return nil, err
+	}
+
+	issue := i.issue(false)
+	issue.State = github.String(i.State)
+
+	return issue, nil
+}
+
+func (p *GitLabProvider) cache(pr *github.PullRequest) {
+	p.mu.Lock()
+	p.merges[pr.GetNumber()] = pr
+	p.mu.Unlock()
+}
+
+// ListTags lists the names of all the tags in the project.
+func (p *GitLabProvider) ListTags(ctx context.Context) ([]string, error) {
+	var all []string
+
+	for path := p.project + "/repository/tags?per_page=100"; path != ""; {
+		var tags []struct {
+			Name string `json:"name"`
+		}
+
+		resp, err := p.api.get(ctx, path, &tags, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tag := range tags {
+			all = append(all, tag.Name)
+		}
+
+		path = nextPage(resp)
+	}
+
+	return all, nil
+}
+
+// TagExists checks if the tag exists in the project.
+func (p *GitLabProvider) TagExists(ctx context.Context, tag string) (bool, error) {
+	_, err := p.GetCommitForTag(ctx, tag)
+	if errors.Is(err, ErrTagNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// GetDefaultBranch gets the name of the default branch of the project.
+func (p *GitLabProvider) GetDefaultBranch(ctx context.Context) (string, error) {
+	project, err := p.getProject(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return project.DefaultBranch, nil
+}
+
+// getProject gets the details of the project, which are only fetched once.
+func (p *GitLabProvider) getProject(ctx context.Context) (*gitLabProject, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.details != nil {
+		return p.details, nil
+	}
+
+	var project gitLabProject
+
+	if _, err := p.api.get(ctx, p.project, &project, nil); err != nil {
+		return nil, err
+	}
+
+	p.details = &project
+
+	return p.details, nil
+}
+
+// GetReleaseByTag returns the release for the given tag, or ErrReleaseNotFound
+// if it doesn't exist.
+func (p *GitLabProvider) GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	var release gitLabRelease
+
+	if _, err := p.api.get(ctx, p.releasePath(tag), &release, ErrReleaseNotFound); err != nil {
+		return nil, err
+	}
+
+	return p.release(ctx, &release)
+}
+
+// CreateOrEditRelease creates the release if it doesn't have an ID, else it
+// edits the existing release. GitLab releases are identified by their tag, so
+// existing releases are given an ID of 0.
+func (p *GitLabProvider) CreateOrEditRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	body := map[string]string{
+		"name":        release.GetName(),
+		"description": release.GetBody(),
+	}
+
+	var output gitLabRelease
+	var err error
+
+	if release.ID == nil {
+		body["tag_name"] = release.GetTagName()
+		_, err = p.api.send(ctx, http.MethodPost, p.project+"/releases", body, &output, ErrReleaseNotFound)
+	} else {
+		_, err = p.api.send(ctx, http.MethodPut, p.releasePath(release.GetTagName()), body, &output, ErrReleaseNotFound)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.release(ctx, &output)
+}
+
+// UploadReleaseAssets uploads the files to the project, and adds links to them
+// to the release.
+func (p *GitLabProvider) UploadReleaseAssets(ctx context.Context, release *github.RepositoryRelease, attachments []string) error {
+	for _, attachment := range attachments {
+		if err := p.uploadReleaseAsset(ctx, release, attachment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *GitLabProvider) uploadReleaseAsset(ctx context.Context, release *github.RepositoryRelease, attachment string) error {
+	f, err := os.OpenFile(attachment, os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := filepath.Clean(filepath.Base(f.Name()))
+
+	for _, asset := range release.Assets {
+		if asset.GetName() == name {
+			return &Error{Kind: ErrAssetExists, Err: fmt.Errorf("release %s already has a link named %q", release.GetTagName(), name)}
+		}
+	}
+
+	project, err := p.getProject(ctx)
+	if err != nil {
+		return err
+	}
+
+	var upload struct {
+		URL string `json:"url"`
+	}
+
+	if _, err := p.api.upload(ctx, p.project+"/uploads", "file", name, f, &upload, nil); err != nil {
+		return err
+	}
+
+	link := map[string]string{
+		"name": name,
+		"url":  project.WebURL + upload.URL,
+	}
+
+	_, err = p.api.send(ctx, http.MethodPost, p.releasePath(release.GetTagName())+"/assets/links", link, nil, ErrReleaseNotFound)
+	return err
+}
+
+// CommitFile creates or updates the file at the path in the project with the
+// content, committing it to the branch with the message. If the branch is
+// empty the default branch is used.
+func (p *GitLabProvider) CommitFile(ctx context.Context, path, branch, message string, content []byte) error {
+	if branch == "" {
+		var err error
+		if branch, err = p.GetDefaultBranch(ctx); err != nil {
+			return err
+		}
+	}
+
+	file := p.project + "/repository/files/" + url.PathEscape(path)
+
+	method := http.MethodPut
+
+	_, err := p.api.get(ctx, file+"?"+url.Values{"ref": {branch}}.Encode(), nil, errNotFound)
+	if errors.Is(err, errNotFound) {
+		method = http.MethodPost
+	} else if err != nil {
+		return err
+	}
+
+	body := map[string]string{
+		"branch":         branch,
+		"content":        string(content),
+		"commit_message": message,
+	}
+
+	_, err = p.api.send(ctx, method, file, body, nil, nil)
+	return err
+}
+
+func (p *GitLabProvider) releasePath(tag string) string {
+	return p.project + "/releases/" + url.PathEscape(tag)
+}
+
+// release converts the release to the GitHub representation.
+func (p *GitLabProvider) release(ctx context.Context, r *gitLabRelease) (*github.RepositoryRelease, error) {
+	release := &github.RepositoryRelease{
+		ID:      github.Int64(0),
+		TagName: github.String(r.TagName),
+		Name:    github.String(r.Name),
+		Body:    github.String(r.Description),
+		HTMLURL: github.String(r.Links.Self),
+	}
+
+	if r.Links.Self == "" {
+		project, err := p.getProject(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		release.HTMLURL = github.String(project.WebURL + "/-/releases/" + url.PathEscape(r.TagName))
+	}
+
+	for _, link := range r.Assets.Links {
+		release.Assets = append(release.Assets, github.ReleaseAsset{
+			Name:               github.String(link.Name),
+			BrowserDownloadURL: github.String(link.URL),
+		})
+	}
+
+	return release, nil
+}
+
+type gitLabProject struct {
+	WebURL        string `json:"web_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type gitLabCommit struct {
+	ID           string    `json:"id"`
+	Message      string    `json:"message"`
+	AuthorName   string    `json:"author_name"`
+	AuthorEmail  string    `json:"author_email"`
+	AuthoredDate time.Time `json:"authored_date"`
+	WebURL       string    `json:"web_url"`
+}
+
+// repositoryCommit converts the commit to the GitHub representation. GitLab
+// does not give the username of the author, so only the name is set.
+func (c *gitLabCommit) repositoryCommit() *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA:     github.String(c.ID),
+		HTMLURL: github.String(c.WebURL),
+		Commit: &github.Commit{
+			SHA:     github.String(c.ID),
+			Message: github.String(c.Message),
+			Author: &github.CommitAuthor{
+				Name:  github.String(c.AuthorName),
+				Email: github.String(c.AuthorEmail),
+				Date:  &c.AuthoredDate,
+			},
+		},
+	}
+}
+
+type gitLabIssue struct {
+	IID             int        `json:"iid"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	WebURL          string     `json:"web_url"`
+	State           string     `json:"state"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	ClosedAt        *time.Time `json:"closed_at"`
+	MergedAt        *time.Time `json:"merged_at"`
+	MergeCommitSHA  string     `json:"merge_commit_sha"`
+	SquashCommitSHA string     `json:"squash_commit_sha"`
+	Author          *struct {
+		Username string `json:"username"`
+	} `json:"author"`
+	Labels []string `json:"labels"`
+}
+
+func (i *gitLabIssue) login() string {
+	if i.Author == nil {
+		return "ghost"
+	}
+
+	return i.Author.Username
+}
+
+// issue converts the issue or merge request to the GitHub representation of an
+// issue. Merge requests are given pull request links, and are closed when they
+// were merged.
+func (i *gitLabIssue) issue(merge bool) *github.Issue {
+	issue := &github.Issue{
+		Number:    github.Int(i.IID),
+		State:     github.String("closed"),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Description),
+		HTMLURL:   github.String(i.WebURL),
+		User:      &github.User{Login: github.String(i.login())},
+		CreatedAt: &i.CreatedAt,
+		UpdatedAt: &i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+	}
+
+	for _, l := range i.Labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l)})
+	}
+
+	if merge {
+		issue.PullRequestLinks = &github.PullRequestLinks{HTMLURL: github.String(i.WebURL)}
+
+		if i.MergedAt != nil {
+			issue.ClosedAt = i.MergedAt
+		}
+	}
+
+	return issue
+}
+
+// pullRequest converts the merge request to the GitHub representation of a
+// pull request.
+func (i *gitLabIssue) pullRequest() *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:    github.Int(i.IID),
+		State:     github.String(i.State),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Description),
+		HTMLURL:   github.String(i.WebURL),
+		User:      &github.User{Login: github.String(i.login())},
+		CreatedAt: &i.CreatedAt,
+		UpdatedAt: &i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+		Merged:    github.Bool(i.State == "merged"),
+		MergedAt:  i.MergedAt,
+	}
+
+	if i.SquashCommitSHA != "" {
+		pr.MergeCommitSHA = github.String(i.SquashCommitSHA)
+	} else if i.MergeCommitSHA != "" {
+		pr.MergeCommitSHA = github.String(i.MergeCommitSHA)
+	}
+
+	for _, l := range i.Labels {
+		pr.Labels = append(pr.Labels, &github.Label{Name: github.String(l)})
+	}
+
+	return pr
+}
+
+type gitLabRelease struct {
+	TagName     string `json:"tag_name"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Links       struct {
+		Self string `json:"self"`
+	} `json:"_links"`
+	Assets struct {
+		Links []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"links"`
+	} `json:"assets"`
+}
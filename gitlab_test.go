@@ -0,0 +1,269 @@
+package releasekit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// newTestGitLabProvider creates a provider for the group/project project on
+// the server.
+func newTestGitLabProvider(t *testing.T, srv *httptest.Server) *GitLabProvider {
+	p, err := NewGitLabProvider(srv.URL+"/api/v4/", "token", nil, "group", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestGitLabProviderGetCommitForTag(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/group%2Fproject/repository/tags/v1.0.0": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("PRIVATE-TOKEN"); got != "token" {
+				t.Errorf("PRIVATE-TOKEN = %q, want the token", got)
+			}
+
+			respond(`{"name": "v1.0.0", "commit": {"id": "c1", "message": "Release", "author_name": "Octo Cat", "web_url": "https://gitlab.com/group/project/-/commit/c1"}}`)(w, r)
+		},
+		"GET /api/v4/projects/group%2Fproject/repository/tags/v2.0.0": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	ctx := context.Background()
+
+	commit, err := p.GetCommitForTag(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if commit.GetSHA() != "c1" || commit.GetCommit().GetMessage() != "Release" {
+		t.Errorf("commit = %v, want c1", commit)
+	}
+
+	if _, err := p.GetCommitForTag(ctx, "v2.0.0"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("got error %v, want ErrTagNotFound", err)
+	}
+}
+
+func TestGitLabProviderListTags(t *testing.T) {
+	var srv *httptest.Server
+
+	srv = newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/group%2Fproject/repository/tags?per_page=100": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects/group%%2Fproject/repository/tags?page=2&per_page=100>; rel="next"`, srv.URL))
+			respond(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`)(w, r)
+		},
+		"GET /api/v4/projects/group%2Fproject/repository/tags?page=2&per_page=100": respond(`[{"name": "v0.1.0"}]`),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	tags, err := p.ListTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"v1.1.0", "v1.0.0", "v0.1.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+}
+
+func TestGitLabProviderGetComparison(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/group%2Fproject/repository/compare?from=v1.0.0&to=v1.1.0": respond(`{
+			"commits": [{"id": "c1", "message": "First"}, {"id": "c2", "message": "Second"}],
+			"diffs": [{"new_path": "README.md"}, {"new_path": "main.go"}]
+		}`),
+		"GET /api/v4/projects/group%2Fproject": respond(`{"web_url": "https://gitlab.com/group/project", "default_branch": "main"}`),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	comparison, err := p.GetComparison(context.Background(), "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if comparison.GetTotalCommits() != 2 || comparison.Commits[1].GetSHA() != "c2" {
+		t.Errorf("commits = %v, want c1 and c2", comparison.Commits)
+	}
+
+	if len(comparison.Files) != 2 || comparison.Files[1].GetFilename() != "main.go" {
+		t.Errorf("files = %v, want README.md and main.go", comparison.Files)
+	}
+
+	if want := "https://gitlab.com/group/project/-/compare/v1.0.0...v1.1.0"; comparison.GetHTMLURL() != want {
+		t.Errorf("URL = %s, want %s", comparison.GetHTMLURL(), want)
+	}
+}
+
+func TestGitLabProviderFetchClosedIssuesSince(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/group%2Fproject/issues?per_page=100&state=closed&updated_after=2020-01-01T00%3A00%3A00Z": respond(`[
+			{"iid": 1, "title": "Crash", "state": "closed", "created_at": "2020-01-02T00:00:00Z", "closed_at": "2020-01-05T00:00:00Z", "author": {"username": "octocat"}, "labels": ["bug"]}
+		]`),
+		"GET /api/v4/projects/group%2Fproject/merge_requests?per_page=100&state=merged&updated_after=2020-01-01T00%3A00%3A00Z": respond(`[
+			{"iid": 1, "title": "Fix crash", "description": "Closes #1", "state": "merged", "created_at": "2020-01-03T00:00:00Z", "merged_at": "2020-01-04T00:00:00Z", "merge_commit_sha": "m1", "squash_commit_sha": "s1"}
+		]`),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	ctx := context.Background()
+
+	issues, err := p.FetchClosedIssuesSince(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues, want 2", len(issues))
+	}
+
+	// the merge request was created after the issue, so is first
+	mr, issue := issues[0], issues[1]
+
+	if !mr.IsPullRequest() || mr.GetTitle() != "Fix crash" || mr.GetUser().GetLogin() != "ghost" {
+		t.Errorf("first issue = %v, want the merge request", mr)
+	}
+
+	if want := time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC); !mr.GetClosedAt().Equal(want) {
+		t.Errorf("merge request closed at %s, want when it was merged", mr.GetClosedAt())
+	}
+
+	if issue.IsPullRequest() || issue.GetNumber() != 1 || issue.Labels[0].GetName() != "bug" {
+		t.Errorf("second issue = %v, want the issue", issue)
+	}
+
+	// the merge request is cached, so getting it doesn't make a request
+	pr, err := p.GetPullRequest(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pr.GetMerged() || pr.GetMergeCommitSHA() != "s1" {
+		t.Errorf("merge request = %v, want merged by the squash commit", pr)
+	}
+}
+
+func TestGitLabProviderGetPullRequestNotFound(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/group%2Fproject/merge_requests/1": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	if _, err := p.GetPullRequest(context.Background(), 1); !errors.Is(err, ErrPullNotFound) {
+		t.Errorf("got error %v, want ErrPullNotFound", err)
+	}
+}
+
+func TestGitLabProviderGetIssue(t *testing.T) {
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"GET /api/v4/projects/other%2Fproject/issues/3": respond(`{"iid": 3, "title": "Opened", "state": "opened"}`),
+		"GET /api/v4/projects/other%2Fproject/issues/4": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	ctx := context.Background()
+
+	issue, err := p.GetIssue(ctx, "other", "project", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issue.GetNumber() != 3 || issue.GetState() != "opened" {
+		t.Errorf("issue = %v, want the opened #3", issue)
+	}
+
+	if _, err := p.GetIssue(ctx, "other", "project", 4); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("got error %v, want ErrIssueNotFound", err)
+	}
+}
+
+func TestGitLabProviderCreateOrEditRelease(t *testing.T) {
+	var bodies []map[string]string
+
+	record := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var b map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				t.Error(err)
+			}
+
+			bodies = append(bodies, b)
+
+			respond(body)(w, r)
+		}
+	}
+
+	srv := newTestServer(t, map[string]http.HandlerFunc{
+		"POST /api/v4/projects/group%2Fproject/releases":       record(`{"tag_name": "v1.0.0", "name": "v1.0.0", "description": "Notes", "_links": {"self": "https://gitlab.com/group/project/-/releases/v1.0.0"}}`),
+		"PUT /api/v4/projects/group%2Fproject/releases/v1.0.0": record(`{"tag_name": "v1.0.0", "name": "v1.0.0", "description": "New notes"}`),
+		"GET /api/v4/projects/group%2Fproject":                 respond(`{"web_url": "https://gitlab.com/group/project"}`),
+		"GET /api/v4/projects/group%2Fproject/releases/v2.0.0": respondStatus(http.StatusNotFound),
+	})
+	defer srv.Close()
+
+	p := newTestGitLabProvider(t, srv)
+
+	ctx := context.Background()
+
+	release, err := p.CreateOrEditRelease(ctx, &github.RepositoryRelease{
+		TagName: github.String("v1.0.0"),
+		Name:    github.String("v1.0.0"),
+		Body:    github.String("Notes"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// GitLab releases are identified by their tag, so they are given an ID
+	// for them to be edited
+	if release.ID == nil || release.GetHTMLURL() != "https://gitlab.com/group/project/-/releases/v1.0.0" {
+		t.Errorf("created release = %v, want an ID and the URL", release)
+	}
+
+	release.Body = github.String("New notes")
+
+	edited, err := p.CreateOrEditRelease(ctx, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.GetBody() != "New notes" || edited.GetHTMLURL() != "https://gitlab.com/group/project/-/releases/v1.0.0" {
+		t.Errorf("edited release = %v, want the new notes and the URL", edited)
+	}
+
+	want := []map[string]string{
+		{"tag_name": "v1.0.0", "name": "v1.0.0", "description": "Notes"},
+		{"name": "v1.0.0", "description": "New notes"},
+	}
+
+	if !reflect.DeepEqual(bodies, want) {
+		t.Errorf("request bodies = %v, want %v", bodies, want)
+	}
+
+	if _, err := p.GetReleaseByTag(ctx, "v2.0.0"); !errors.Is(err, ErrReleaseNotFound) {
+		t.Errorf("got error %v, want ErrReleaseNotFound", err)
+	}
+}
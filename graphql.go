@@ -0,0 +1,659 @@
+package releasekit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+const graphQLCommitFragment = `
+fragment commit on Commit {
+  oid
+  url
+  message
+  author { name email date user { login } }
+}`
+
+const graphQLPullRequestFields = `
+number
+title
+url
+body
+createdAt
+updatedAt
+closedAt
+merged
+mergedAt
+mergeCommit { oid }
+baseRefName
+author { login }
+labels(first: 100) { nodes { name } }
+closingIssuesReferences(first: 25) { nodes { number repository { name owner { login } } } }`
+
+const graphQLTagQuery = `
+query($owner: String!, $repo: String!, $ref: String!) {
+  repository(owner: $owner, name: $repo) {
+    ref(qualifiedName: $ref) {
+      target {
+        __typename
+        ...commit
+        ... on Tag { target { __typename ...commit } }
+      }
+    }
+  }
+}` + graphQLCommitFragment
+
+const graphQLCommitQuery = `
+query($owner: String!, $repo: String!, $ref: String!) {
+  repository(owner: $owner, name: $repo) {
+    object(expression: $ref) { __typename ...commit }
+  }
+}` + graphQLCommitFragment
+
+const graphQLIssuesQuery = `
+query($owner: String!, $repo: String!, $since: DateTime, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    issues(first: 100, after: $cursor, states: CLOSED, filterBy: {since: $since}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        number
+        title
+        url
+        body
+        createdAt
+        updatedAt
+        closedAt
+        author { login }
+        labels(first: 100) { nodes { name } }
+      }
+    }
+  }
+}`
+
+const graphQLPullRequestsQuery = `
+query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequests(first: 100, after: $cursor, states: [CLOSED, MERGED], orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {` + graphQLPullRequestFields + `
+      }
+    }
+  }
+}`
+
+const graphQLPullRequestQuery = `
+query($owner: String!, $repo: String!, $number: Int!) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $number) {` + graphQLPullRequestFields + `
+    }
+  }
+}`
+
+const graphQLTagsQuery = `
+query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    refs(refPrefix: "refs/tags/", first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes { name }
+    }
+  }
+}`
+
+// graphQLCommitsPerQuery is the number of commits the associated pull requests
+// are listed for in each query.
+const graphQLCommitsPerQuery = 50
+
+// GraphQLError is an error returned in the response to a GitHub GraphQL API
+// query.
+type GraphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Error returns the error message.
+func (e *GraphQLError) Error() string {
+	return e.Message
+}
+
+// GraphQLSource is a Source that uses the GitHub GraphQL API, which fetches the
+// closed issues and pull requests with their merged state in a few paginated
+// queries instead of a request per pull request, and the pull requests
+// associated with commits in a query per 50 commits. The first commit and
+// commit comparison are not available using the GraphQL API, so are fetched
+// using the REST API, along with the files changed by the commits.
+type GraphQLSource struct {
+	*RESTSource
+
+	mu    sync.Mutex
+	pulls map[int]*github.PullRequest
+}
+
+// NewGraphQLSource creates a new source for the repository using the GitHub
+// GraphQL API.
+func NewGraphQLSource(c *github.Client, owner, repo string) *GraphQLSource {
+	return &GraphQLSource{
+		RESTSource: NewRESTSource(c, owner, repo),
+		pulls:      make(map[int]*github.PullRequest),
+	}
+}
+
+// GetCommitForTag gets the commit a tag is a reference to.
+func (s *GraphQLSource) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	var data struct {
+		Repository struct {
+			Ref *struct {
+				Target graphQLCommit `json:"target"`
+			} `json:"ref"`
+		} `json:"repository"`
+	}
+
+	vars := map[string]interface{}{"ref": "refs/tags/" + tag}
+
+	if err := s.query(ctx, graphQLTagQuery, vars, &data, ErrTagNotFound); err != nil {
+		return nil, err
+	}
+
+	if data.Repository.Ref == nil {
+		return nil, &Error{Kind: ErrTagNotFound, Err: fmt.Errorf("no tag named %q", tag)}
+	}
+
+	target := &data.Repository.Ref.Target
+	if target.Typename == "Tag" && target.Target != nil {
+		target = target.Target
+	}
+
+	if target.Typename != "Commit" {
+		return nil, &Error{Kind: ErrTagNotFound, Err: fmt.Errorf("tag %q does not point to a commit", tag)}
+	}
+
+	return target.repositoryCommit(), nil
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+func (s *GraphQLSource) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	var data struct {
+		Repository struct {
+			Object *graphQLCommit `json:"object"`
+		} `json:"repository"`
+	}
+
+	if err := s.query(ctx, graphQLCommitQuery, map[string]interface{}{"ref": ref}, &data, nil); err != nil {
+		return nil, err
+	}
+
+	if data.Repository.Object == nil || data.Repository.Object.Typename != "Commit" {
+		return nil, fmt.Errorf("no commit for %q", ref)
+	}
+
+	return data.Repository.Object.repositoryCommit(), nil
+}
+
+// FetchClosedIssuesSince fetches all closed issues and pull requests since the
+// specified time, newest first. The merged state of the pull requests is
+// cached, so getting them afterwards does not make any requests.
+func (s *GraphQLSource) FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error) {
+	var all []*github.Issue
+
+	vars := map[string]interface{}{"since": nil, "cursor": nil}
+	if !since.IsZero() {
+		vars["since"] = since
+	}
+
+	for {
+		var data struct {
+			Repository struct {
+				Issues graphQLIssueConnection `json:"issues"`
+			} `json:"repository"`
+		}
+
+		if err := s.query(ctx, graphQLIssuesQuery, vars, &data, nil); err != nil {
+			return nil, err
+		}
+
+		for _, node := range data.Repository.Issues.Nodes {
+			all = append(all, node.issue(nil))
+		}
+
+		if !data.Repository.Issues.PageInfo.HasNextPage {
+			break
+		}
+
+		vars["cursor"] = data.Repository.Issues.PageInfo.EndCursor
+	}
+
+	vars = map[string]interface{}{"cursor": nil}
+
+	for done := false; !done; {
+		var data struct {
+			Repository struct {
+				PullRequests graphQLIssueConnection `json:"pullRequests"`
+			} `json:"repository"`
+		}
+
+		if err := s.query(ctx, graphQLPullRequestsQuery, vars, &data, nil); err != nil {
+			return nil, err
+		}
+
+		for _, node := range data.Repository.PullRequests.Nodes {
+			// pull requests are ordered by when they were updated, so the rest
+			// were updated before the time
+			if node.UpdatedAt.Before(since) {
+				done = true
+				break
+			}
+
+			node.addClosingReferences(s.Owner, s.Repo)
+
+			s.cache(node.pullRequest())
+			all = append(all, node.issue(s.pullRequestLinks(node.Number)))
+		}
+
+		if !data.Repository.PullRequests.PageInfo.HasNextPage {
+			break
+		}
+
+		vars["cursor"] = data.Repository.PullRequests.PageInfo.EndCursor
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].GetCreatedAt().After(all[j].GetCreatedAt())
+	})
+
+	return all, nil
+}
+
+// GetPullRequest gets the pull request with the specified number, from the
+// pull requests already fetched if possible.
+func (s *GraphQLSource) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	s.mu.Lock()
+	pr, ok := s.pulls[number]
+	s.mu.Unlock()
+
+	if ok {
+		return pr, nil
+	}
+
+	var data struct {
+		Repository struct {
+			PullRequest *graphQLIssue `json:"pullRequest"`
+		} `json:"repository"`
+	}
+
+	if err := s.query(ctx, graphQLPullRequestQuery, map[string]interface{}{"number": number}, &data, ErrPullNotFound); err != nil {
+		return nil, err
+	}
+
+	if data.Repository.PullRequest == nil {
+		return nil, &Error{Kind: ErrPullNotFound, Err: fmt.Errorf("no pull request #%d", number)}
+	}
+
+	data.Repository.PullRequest.addClosingReferences(s.Owner, s.Repo)
+
+	pr = data.Repository.PullRequest.pullRequest()
+	s.cache(pr)
+
+	return pr, nil
+}
+
+// ListTags lists the names of all the tags in the repository.
+func (s *GraphQLSource) ListTags(ctx context.Context) ([]string, error) {
+	var tags []string
+
+	vars := map[string]interface{}{"cursor": nil}
+
+	for {
+		var data struct {
+			Repository struct {
+				Refs struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						Name string `json:"name"`
+					} `json:"nodes"`
+				} `json:"refs"`
+			} `json:"repository"`
+		}
+
+		if err := s.query(ctx, graphQLTagsQuery, vars, &data, nil); err != nil {
+			return nil, err
+		}
+
+		for _, node := range data.Repository.Refs.Nodes {
+			tags = append(tags, node.Name)
+		}
+
+		if !data.Repository.Refs.PageInfo.HasNextPage {
+			break
+		}
+
+		vars["cursor"] = data.Repository.Refs.PageInfo.EndCursor
+	}
+
+	return tags, nil
+}
+
+// ListPullRequestsForCommits lists the merged pull requests associated with
+// each of the commits, using a query per 50 commits.
+func (s *GraphQLSource) ListPullRequestsForCommits(ctx context.Context, shas []string) (map[string][]*github.PullRequest, error) {
+	pulls := make(map[string][]*github.PullRequest)
+
+	for start := 0; start < len(shas); start += graphQLCommitsPerQuery {
+		end := start + graphQLCommitsPerQuery
+		if end > len(shas) {
+			end = len(shas)
+		}
+
+		batch := shas[start:end]
+
+		var params, fields strings.Builder
+
+		vars := make(map[string]interface{})
+
+		for i, sha := range batch {
+			fmt.Fprintf(&params, ", $c%d: GitObjectID!", i)
+			fmt.Fprintf(&fields, `
+    c%d: object(oid: $c%d) {
+      ... on Commit {
+        associatedPullRequests(first: 10) { nodes {%s } }
+      }
+    }`, i, i, graphQLPullRequestFields)
+
+			vars[fmt.Sprintf("c%d", i)] = sha
+		}
+
+		query := fmt.Sprintf(`
+query($owner: String!, $repo: String!%s) {
+  repository(owner: $owner, name: $repo) {%s
+  }
+}`, params.String(), fields.String())
+
+		var data struct {
+			Repository map[string]*struct {
+				AssociatedPullRequests struct {
+					Nodes []*graphQLIssue `json:"nodes"`
+				} `json:"associatedPullRequests"`
+			} `json:"repository"`
+		}
+
+		if err := s.query(ctx, query, vars, &data, nil); err != nil {
+			return nil, err
+		}
+
+		for i, sha := range batch {
+			commit := data.Repository[fmt.Sprintf("c%d", i)]
+			if commit == nil {
+				continue
+			}
+
+			for _, node := range commit.AssociatedPullRequests.Nodes {
+				if !node.Merged {
+					continue
+				}
+
+				node.addClosingReferences(s.Owner, s.Repo)
+
+				pr := node.pullRequest()
+				s.cache(pr)
+
+				pulls[sha] = append(pulls[sha], pr)
+			}
+		}
+	}
+
+	return pulls, nil
+}
+
+func (s *GraphQLSource) cache(pr *github.PullRequest) {
+	s.mu.Lock()
+	s.pulls[pr.GetNumber()] = pr
+	s.mu.Unlock()
+}
+
+// pullRequestLinks builds the links that mark an issue as a pull request, the
+// same as the REST API.
+func (s *GraphQLSource) pullRequestLinks(number int) *github.PullRequestLinks {
+	url := fmt.Sprintf("%srepos/%s/%s/pulls/%d", s.Client.BaseURL, s.Owner, s.Repo, number)
+	return &github.PullRequestLinks{URL: &url}
+}
+
+// graphQLURL returns the URL of the GraphQL API for the client, which for
+// GitHub Enterprise is /api/graphql instead of /api/v3/graphql.
+func (s *GraphQLSource) graphQLURL() string {
+	base := s.Client.BaseURL.String()
+
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+
+	return base + "graphql"
+}
+
+// query makes the GraphQL query with the variables, along with the owner and
+// name of the repository, and decodes the response data into data. Errors in
+// the response are returned, using notFound as the kind for not found errors.
+func (s *GraphQLSource) query(ctx context.Context, query string, vars map[string]interface{}, data interface{}, notFound error) error {
+	variables := map[string]interface{}{"owner": s.Owner, "repo": s.Repo}
+	for k, v := range vars {
+		variables[k] = v
+	}
+
+	body := map[string]interface{}{"query": query, "variables": variables}
+
+	req, err := s.Client.NewRequest("POST", s.graphQLURL(), body)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []*GraphQLError `json:"errors"`
+	}
+
+	if _, err := s.Client.Do(ctx, req, &resp); err != nil {
+		return wrapError(err, notFound)
+	}
+
+	if len(resp.Errors) > 0 {
+		err := resp.Errors[0]
+
+		switch err.Type {
+		case "NOT_FOUND":
+			if notFound != nil {
+				return &Error{Kind: notFound, Err: err}
+			}
+		case "RATE_LIMITED":
+			return &Error{Kind: ErrRateLimited, Err: err}
+		case "FORBIDDEN":
+			return &Error{Kind: ErrPermissionDenied, Err: err}
+		}
+
+		return err
+	}
+
+	return json.Unmarshal(resp.Data, data)
+}
+
+type graphQLActor struct {
+	Login string `json:"login"`
+}
+
+type graphQLCommit struct {
+	Typename string `json:"__typename"`
+	OID      string `json:"oid"`
+	URL      string `json:"url"`
+	Message  string `json:"message"`
+	Author   struct {
+		Name  string        `json:"name"`
+		Email string        `json:"email"`
+		Date  time.Time     `json:"date"`
+		User  *graphQLActor `json:"user"`
+	} `json:"author"`
+	Target *graphQLCommit `json:"target"`
+}
+
+// repositoryCommit converts the commit to the REST API representation.
+func (c *graphQLCommit) repositoryCommit() *github.RepositoryCommit {
+	commit := &github.RepositoryCommit{
+		SHA:     github.String(c.OID),
+		HTMLURL: github.String(c.URL),
+		Commit: &github.Commit{
+			SHA:     github.String(c.OID),
+			Message: github.String(c.Message),
+			Author: &github.CommitAuthor{
+				Name:  github.String(c.Author.Name),
+				Email: github.String(c.Author.Email),
+				Date:  &c.Author.Date,
+			},
+		},
+	}
+
+	if c.Author.User != nil {
+		commit.Author = &github.User{Login: github.String(c.Author.User.Login)}
+	}
+
+	return commit
+}
+
+type graphQLIssueConnection struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []*graphQLIssue `json:"nodes"`
+}
+
+type graphQLIssue struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Body        string     `json:"body"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	ClosedAt    *time.Time `json:"closedAt"`
+	Merged      bool       `json:"merged"`
+	MergedAt    *time.Time `json:"mergedAt"`
+	MergeCommit *struct {
+		OID string `json:"oid"`
+	} `json:"mergeCommit"`
+	BaseRefName string        `json:"baseRefName"`
+	Author      *graphQLActor `json:"author"`
+	Labels      struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	ClosingIssuesReferences struct {
+		Nodes []struct {
+			Number     int `json:"number"`
+			Repository struct {
+				Name  string       `json:"name"`
+				Owner graphQLActor `json:"owner"`
+			} `json:"repository"`
+		} `json:"nodes"`
+	} `json:"closingIssuesReferences"`
+}
+
+// addClosingReferences adds the issues the pull request closes that are not
+// closed by its body, such as issues linked in the sidebar, to the end of the
+// body as "Closes #N", so they are found the same as using the REST API.
+func (i *graphQLIssue) addClosingReferences(owner, repo string) {
+	seen := make(map[string]bool)
+
+	for _, ref := range ParseClosingReferences(i.Body) {
+		if ref.In(owner, repo) {
+			ref.Owner, ref.Repo = "", ""
+		}
+
+		seen[strings.ToLower(ref.String())] = true
+	}
+
+	for _, node := range i.ClosingIssuesReferences.Nodes {
+		ref := Reference{Owner: node.Repository.Owner.Login, Repo: node.Repository.Name, Number: node.Number}
+		if ref.In(owner, repo) {
+			ref.Owner, ref.Repo = "", ""
+		}
+
+		if key := strings.ToLower(ref.String()); !seen[key] {
+			seen[key] = true
+			i.Body += "\n\nCloses " + ref.String()
+		}
+	}
+}
+
+// login returns the login of the author, which is "ghost" for deleted users
+// the same as the REST API.
+func (i *graphQLIssue) login() string {
+	if i.Author == nil {
+		return "ghost"
+	}
+
+	return i.Author.Login
+}
+
+func (i *graphQLIssue) labels() []github.Label {
+	var labels []github.Label
+
+	for _, l := range i.Labels.Nodes {
+		labels = append(labels, github.Label{Name: github.String(l.Name)})
+	}
+
+	return labels
+}
+
+// issue converts the issue or pull request to the REST API representation of
+// an issue. Pull requests are given their pull request links.
+func (i *graphQLIssue) issue(links *github.PullRequestLinks) *github.Issue {
+	return &github.Issue{
+		Number:           github.Int(i.Number),
+		State:            github.String("closed"),
+		Title:            github.String(i.Title),
+		Body:             github.String(i.Body),
+		HTMLURL:          github.String(i.URL),
+		User:             &github.User{Login: github.String(i.login())},
+		Labels:           i.labels(),
+		CreatedAt:        &i.CreatedAt,
+		UpdatedAt:        &i.UpdatedAt,
+		ClosedAt:         i.ClosedAt,
+		PullRequestLinks: links,
+	}
+}
+
+// pullRequest converts the pull request to the REST API representation.
+func (i *graphQLIssue) pullRequest() *github.PullRequest {
+	pr := &github.PullRequest{
+		Number:    github.Int(i.Number),
+		State:     github.String("closed"),
+		Title:     github.String(i.Title),
+		Body:      github.String(i.Body),
+		HTMLURL:   github.String(i.URL),
+		User:      &github.User{Login: github.String(i.login())},
+		CreatedAt: &i.CreatedAt,
+		UpdatedAt: &i.UpdatedAt,
+		ClosedAt:  i.ClosedAt,
+		Merged:    github.Bool(i.Merged),
+		MergedAt:  i.MergedAt,
+	}
+
+	if i.MergeCommit != nil {
+		pr.MergeCommitSHA = github.String(i.MergeCommit.OID)
+	}
+
+	if i.BaseRefName != "" {
+		pr.Base = &github.PullRequestBranch{Ref: github.String(i.BaseRefName)}
+	}
+
+	for _, l := range i.labels() {
+		l := l
+		pr.Labels = append(pr.Labels, &l)
+	}
+
+	return pr
+}
@@ -8,7 +8,7 @@ import (
 )
 
 // FetchClosedIssuesSince fetches all closed issues since the specified time.
-func FetchClosedIssuesSince(c *github.Client, owner, repo string, since time.Time) ([]*github.Issue, error) {
+func FetchClosedIssuesSince(ctx context.Context, c *github.Client, owner, repo string, since time.Time) ([]*github.Issue, error) {
 	opt := &github.IssueListByRepoOptions{
 		State: "closed",
 		Since: since,
@@ -17,9 +17,9 @@ func FetchClosedIssuesSince(c *github.Client, owner, repo string, since time.Tim
 	var allIssues []*github.Issue
 
 	for {
-		issues, resp, err := c.Issues.ListByRepo(context.Background(), owner, repo, opt)
+		issues, resp, err := c.Issues.ListByRepo(ctx, owner, repo, opt)
 		if err != nil {
-			return nil, err
+			return nil, wrapError(err, nil)
 		}
 
 		allIssues = append(allIssues, issues...)
@@ -33,3 +33,14 @@ func FetchClosedIssuesSince(c *github.Client, owner, repo string, since time.Tim
 
 	return allIssues, nil
 }
+
+// GetIssue gets the issue with the specified number, which can be in any
+// repository the client can access.
+func GetIssue(ctx context.Context, c *github.Client, owner, repo string, number int) (*github.Issue, error) {
+	issue, _, err := c.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, wrapError(err, ErrIssueNotFound)
+	}
+
+	return issue, nil
+}
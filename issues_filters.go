@@ -1,37 +1,157 @@
 package releasekit
 
 import (
-	"log"
+	"context"
+	"errors"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v18/github"
 )
 
 const (
-	closedByPullRequestRegex = `(?i)(close|closes|closed|resolve|resolves|resolved|fix|fixes|fixed) #([0-9]+)`
-	mergedPullRequestRegex   = `(?i)merge pull request #([0-9]+)|\(#([0-9]+)\)" \(#([0-9]+)\)|\(#([0-9]+)\)`
+	mergedPullRequestRegex  = `(?i)merge pull request #([0-9]+)|\(#([0-9]+)\)" \(#([0-9]+)\)|\(#([0-9]+)\)`
+	mergedMergeRequestRegex = `See merge request \S*!([0-9]+)`
 )
 
+// ExclusionRules are the rules for keeping issues and pull requests out of the
+// release notes, such as ones with a skip-changelog label or opened by bots.
+type ExclusionRules struct {
+	// Labels excludes issues and pull requests with any of the labels.
+	Labels []string
+
+	// Authors excludes issues and pull requests opened by any of the users.
+	// Bot users match with or without the [bot] suffix.
+	Authors []string
+
+	// Titles excludes issues and pull requests with a title matching any of
+	// the patterns.
+	Titles []*regexp.Regexp
+
+	// Include is the numbers of issues and pull requests that are never
+	// excluded by the other rules.
+	Include []int
+
+	// Exclude is the numbers of issues and pull requests that are always
+	// excluded.
+	Exclude []int
+}
+
+// Excludes returns true if the issue or pull request is excluded by the rules.
+func (r *ExclusionRules) Excludes(issue *github.Issue) bool {
+	return !r.decide(issue).Keep
+}
+
+// decide returns the decision to keep or drop the issue using the rules.
+func (r *ExclusionRules) decide(issue *github.Issue) Decision {
+	number := issue.GetNumber()
+
+	switch {
+	case contains(r.Exclude, number):
+		return drop(issue, "excluded by number")
+	case contains(r.Include, number):
+		return keep(issue, "included by number")
+	}
+
+	for _, l := range issue.Labels {
+		for _, label := range r.Labels {
+			if strings.EqualFold(l.GetName(), label) {
+				return drop(issue, "has the %s label", l.GetName())
+			}
+		}
+	}
+
+	login := issue.GetUser().GetLogin()
+
+	for _, author := range r.Authors {
+		if strings.EqualFold(login, author) || strings.EqualFold(strings.TrimSuffix(login, "[bot]"), author) {
+			return drop(issue, "opened by %s", login)
+		}
+	}
+
+	for _, title := range r.Titles {
+		if title.MatchString(issue.GetTitle()) {
+			return drop(issue, "title matches %s", title)
+		}
+	}
+
+	return keep(issue, "not excluded")
+}
+
+// ExcludedFilter returns a filter that drops the issues and pull requests
+// excluded by the rules.
+func ExcludedFilter(rules *ExclusionRules) Filter {
+	return KeepFunc("excluded", func(fc *FilterContext, issue *github.Issue) (bool, string) {
+		d := rules.decide(issue)
+		return d.Keep, d.Reason
+	})
+}
+
+// FilterExcluded filters out all issues and pull requests excluded by the
+// rules.
+func FilterExcluded(issues []*github.Issue, rules *ExclusionRules) []*github.Issue {
+	var filtered []*github.Issue
+
+	for _, issue := range issues {
+		if !rules.Excludes(issue) {
+			filtered = append(filtered, issue)
+		}
+	}
+
+	return filtered
+}
+
+// ClosedBeforeFilter returns a filter that drops the issues closed before the
+// Since time of the context.
+func ClosedBeforeFilter() Filter {
+	return NewFilter("closed-before", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return closedBefore(issues, fc.Since), nil
+	})
+}
+
 // FilterClosedBefore filters out all issues that were closed after the
 // specified time.
 func FilterClosedBefore(issues []*github.Issue, d time.Time) []*github.Issue {
-	var filtered []*github.Issue
+	return kept(closedBefore(issues, d))
+}
+
+func closedBefore(issues []*github.Issue, d time.Time) []Decision {
+	var decisions []Decision
 
 	for _, issue := range issues {
 		if issue.ClosedAt.After(d) {
-			filtered = append(filtered, issue)
+			decisions = append(decisions, keep(issue, "closed after %s", d.Format(time.RFC3339)))
+		} else {
+			decisions = append(decisions, drop(issue, "closed too early, before %s", d.Format(time.RFC3339)))
 		}
 	}
 
-	return filtered
+	return decisions
+}
+
+// ClosedAfterFilter returns a filter that drops the issues closed after the
+// head commit of the context. If the context has no head commit every issue is
+// kept.
+func ClosedAfterFilter() Filter {
+	return NewFilter("closed-after", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		if fc.Head == nil {
+			return closedAfter(issues, time.Now()), nil
+		}
+
+		return closedAfter(issues, fc.Head.GetCommit().GetAuthor().GetDate()), nil
+	})
 }
 
 // FilterClosedAfter filters out all issues that were closed before the
 // specified time.
 func FilterClosedAfter(issues []*github.Issue, d time.Time) []*github.Issue {
-	var filtered []*github.Issue
+	return kept(closedAfter(issues, d))
+}
+
+func closedAfter(issues []*github.Issue, d time.Time) []Decision {
+	var decisions []Decision
 
 	// add some leniency for difference between tagging/merging pull request
 	// creating the commit
@@ -39,19 +159,31 @@ func FilterClosedAfter(issues []*github.Issue, d time.Time) []*github.Issue {
 
 	for _, issue := range issues {
 		if issue.ClosedAt.Before(time) {
-			filtered = append(filtered, issue)
+			decisions = append(decisions, keep(issue, "closed before the next tag"))
+		} else {
+			decisions = append(decisions, drop(issue, "closed too late, after the next tag"))
 		}
 	}
 
-	return filtered
+	return decisions
+}
+
+// ClosedByPullFilter returns a filter that drops the issues in the repository
+// of the context that were closed automatically by one of the pull requests.
+func ClosedByPullFilter() Filter {
+	return NewFilter("closed-by-pull", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return closedByPull(issues, fc.Owner, fc.Repo), nil
+	})
 }
 
-// FilterClosedByPull filters out all issues that were closed automatically
-// by a pull request.
-func FilterClosedByPull(issues []*github.Issue) []*github.Issue {
-	r, _ := regexp.Compile(closedByPullRequestRegex)
+// FilterClosedByPull filters out all issues in the repository that were closed
+// automatically by a pull request.
+func FilterClosedByPull(issues []*github.Issue, owner, repo string) []*github.Issue {
+	return kept(closedByPull(issues, owner, repo))
+}
 
-	var ignore []int
+func closedByPull(issues []*github.Issue, owner, repo string) []Decision {
+	closedBy := make(map[int]int)
 
 	for _, issue := range issues {
 		if !issue.IsPullRequest() {
@@ -62,130 +194,453 @@ func FilterClosedByPull(issues []*github.Issue) []*github.Issue {
 			continue
 		}
 
-		matches := r.FindStringSubmatch(*issue.Body)
-		if matches != nil {
-			num, _ := strconv.Atoi(matches[len(matches)-1])
-			ignore = append(ignore, num)
+		for _, num := range closedIssueNumbers(*issue.Body, owner, repo) {
+			if _, ok := closedBy[num]; !ok {
+				closedBy[num] = *issue.Number
+			}
 		}
 	}
 
-	var filtered []*github.Issue
+	var decisions []Decision
 
 	for _, issue := range issues {
-		if !contains(ignore, *issue.Number) {
-			filtered = append(filtered, issue)
+		// GitLab numbers issues and merge requests separately, so only issues
+		// are ignored
+		if issue.IsPullRequest() {
+			decisions = append(decisions, keep(issue, "pull request"))
+		} else if pr, ok := closedBy[*issue.Number]; ok {
+			decisions = append(decisions, drop(issue, "closed by pull request #%d", pr))
+		} else {
+			decisions = append(decisions, keep(issue, "not closed by a pull request"))
 		}
 	}
 
-	return filtered
+	return decisions
+}
+
+// NonMergedPullsFilter returns a filter that drops the pull requests that were
+// closed and not merged, fetching them using the cache of the context.
+func NonMergedPullsFilter() Filter {
+	return NewFilter("non-merged-pulls", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return nonMergedPulls(ctx, issues, fc.Pulls)
+	})
+}
+
+// FilterNonMergedPulls filters out all pull requests that were closed and not
+// merged. The pull requests are fetched concurrently using the cache, so this
+// should be the last filter applied to avoid fetching pull requests that are
+// filtered out by the others.
+func FilterNonMergedPulls(ctx context.Context, issues []*github.Issue, pulls *PullRequestCache) ([]*github.Issue, error) {
+	decisions, err := nonMergedPulls(ctx, issues, pulls)
+	if err != nil {
+		return nil, err
+	}
+
+	return kept(decisions), nil
 }
 
-// FilterNonMergedPulls filters out all pull requests that were closed and not merged.
-func FilterNonMergedPulls(issues []*github.Issue, c *github.Client, owner, repo string) []*github.Issue {
-	var ignore []int
+func nonMergedPulls(ctx context.Context, issues []*github.Issue, pulls *PullRequestCache) ([]Decision, error) {
+	var numbers []int
+
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			numbers = append(numbers, *issue.Number)
+		}
+	}
+
+	if err := pulls.Prefetch(ctx, numbers); err != nil {
+		return nil, err
+	}
+
+	var decisions []Decision
 
 	for _, issue := range issues {
 		if !issue.IsPullRequest() {
+			decisions = append(decisions, keep(issue, "issue"))
 			continue
 		}
 
-		pr, err := GetPullRequest(c, owner, repo, *issue.Number)
+		pr, err := pulls.Get(ctx, *issue.Number)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		if !*pr.Merged {
-			ignore = append(ignore, *issue.Number)
+		if pr.GetMerged() {
+			decisions = append(decisions, keep(issue, "merged"))
+		} else {
+			decisions = append(decisions, drop(issue, "not merged"))
 		}
 	}
 
-	var filtered []*github.Issue
+	return decisions, nil
+}
+
+// InComparisonFilter returns a filter that keeps only the issues and pull
+// requests in the commits of the context, in the same way as
+// FilterInComparison.
+func InComparisonFilter() Filter {
+	return NewFilter("in-comparison", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return inComparison(ctx, fc, issues)
+	})
+}
+
+// FilterInComparison filters out any issues and pull requests not merged or
+// closed by one of the commits, adding the missing ones they merged or closed.
+func FilterInComparison(ctx context.Context, issues []*github.Issue, pulls *PullRequestCache, commits []github.RepositoryCommit, owner, repo string) ([]*github.Issue, error) {
+	fc := &FilterContext{Pulls: pulls, Owner: owner, Repo: repo, Commits: commits}
+
+	decisions, err := inComparison(ctx, fc, issues)
+	if err != nil {
+		return nil, err
+	}
+
+	return kept(decisions), nil
+}
+
+func inComparison(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+	shas := make(map[string]bool)
+	merged := make(map[int]string)
+	closed := make(map[int]string)
+
+	for _, c := range fc.Commits {
+		shas[c.GetSHA()] = true
+
+		if num, ok := mergedPullNumber(c.GetCommit().GetMessage()); ok {
+			merged[num] = c.GetSHA()
+		}
+
+		for _, num := range closedIssueNumbers(c.GetCommit().GetMessage(), fc.Owner, fc.Repo) {
+			closed[num] = c.GetSHA()
+		}
+	}
+
+	pulls := fc.Pulls
+	list := unattributedCommits(fc.Commits)
+
+	if err := pulls.PrefetchCommits(ctx, list); err != nil {
+		return nil, err
+	}
+
+	var associated []*github.PullRequest
+
+	// the commit each associated pull request was found for
+	associatedBy := make(map[int]string)
+
+	for _, sha := range list {
+		prs, err := pulls.ForCommit(ctx, sha)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pr := range prs {
+			// pull requests that merged the commit into another branch, such
+			// as backports, are associated with it too
+			if !shas[pr.GetMergeCommitSHA()] {
+				continue
+			}
+
+			if _, ok := associatedBy[pr.GetNumber()]; !ok {
+				associatedBy[pr.GetNumber()] = sha
+				associated = append(associated, pr)
+			}
+		}
+	}
+
+	// the pull requests are only fetched if whether they are in the
+	// comparison can't be decided from the commits, which is when they are
+	// not merged by a commit message and the associated pull requests can't
+	// be listed
+	listed := pulls.ListsCommits()
+
+	var undecided []int
 
 	for _, issue := range issues {
-		if !contains(ignore, *issue.Number) {
-			filtered = append(filtered, issue)
+		n := issue.GetNumber()
+
+		if issue.IsPullRequest() && !listed && associatedBy[n] == "" && merged[n] == "" {
+			undecided = append(undecided, n)
 		}
 	}
 
-	return filtered
-}
+	if err := pulls.Prefetch(ctx, undecided); err != nil {
+		return nil, err
+	}
 
-// FilterClosedByCommits filters out any issues that have not been closed
-// by commit messages.
-func FilterClosedByCommits(issues []*github.Issue, commits []github.RepositoryCommit) []*github.Issue {
-	r, _ := regexp.Compile(closedByPullRequestRegex)
+	var decisions []Decision
 
-	var ignore []int
+	seen := make(map[int]bool)
 
-	for _, c := range commits {
-		matches := r.FindStringSubmatch(*c.Commit.Message)
-		if matches != nil {
-			num, _ := strconv.Atoi(matches[len(matches)-1])
-			ignore = append(ignore, num)
+	for _, issue := range issues {
+		if !issue.IsPullRequest() {
+			if sha, ok := closed[*issue.Number]; ok {
+				decisions = append(decisions, keep(issue, "closed by commit %s", shortSHA(sha)))
+			} else {
+				decisions = append(decisions, drop(issue, "not closed by a commit in the comparison"))
+			}
+
+			continue
+		}
+
+		seen[*issue.Number] = true
+
+		switch {
+		case associatedBy[*issue.Number] != "":
+			decisions = append(decisions, keep(issue, "associated with commit %s", shortSHA(associatedBy[*issue.Number])))
+			continue
+		case merged[*issue.Number] != "":
+			decisions = append(decisions, keep(issue, "merged by commit %s", shortSHA(merged[*issue.Number])))
+			continue
+		case listed:
+			decisions = append(decisions, drop(issue, "not merged by or associated with a commit in the comparison"))
+			continue
+		}
+
+		pr, err := pulls.Get(ctx, *issue.Number)
+		if err != nil {
+			return nil, err
+		}
+
+		sha := pr.GetMergeCommitSHA()
+
+		switch {
+		case !pr.GetMerged():
+			decisions = append(decisions, drop(issue, "not merged"))
+		case sha != "" && shas[sha]:
+			decisions = append(decisions, keep(issue, "merge commit %s is in the comparison", shortSHA(sha)))
+		case sha != "":
+			decisions = append(decisions, drop(issue, "merge commit %s is not in the comparison", shortSHA(sha)))
+		default:
+			decisions = append(decisions, drop(issue, "not merged by a commit in the comparison"))
 		}
 	}
 
-	var filtered []*github.Issue
+	for _, pr := range associated {
+		if !seen[pr.GetNumber()] {
+			seen[pr.GetNumber()] = true
+			decisions = append(decisions, keep(pullRequestIssue(pr), "added, associated with commit %s", shortSHA(associatedBy[pr.GetNumber()])))
+		}
+	}
+
+	added, err := missingFromComparison(ctx, fc, issues, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(decisions, added...), nil
+}
+
+// missingFromComparison returns the decisions to add the pull requests merged
+// by the commit messages and the issues closed by them that are missing from
+// the issues, such as those closed long before the base commit was made. The
+// pull requests and issues are fetched, and only kept if they were merged or
+// closed. Numbers that are not pull requests or issues are ignored, as are the
+// issues if the source of the context is not an IssueGetter. The pulls are the
+// numbers of the pull requests already decided on.
+func missingFromComparison(ctx context.Context, fc *FilterContext, issues []*github.Issue, pulls map[int]bool) ([]Decision, error) {
+	// GitLab numbers issues and merge requests separately, so the issues are
+	// kept apart from the pull requests
+	seen := make(map[int]bool)
 
 	for _, issue := range issues {
-		if contains(ignore, *issue.Number) || issue.IsPullRequest() {
-			filtered = append(filtered, issue)
+		if !issue.IsPullRequest() {
+			seen[issue.GetNumber()] = true
 		}
 	}
 
-	return filtered
+	var merged, closed []int
+
+	mergedBy := make(map[int]string)
+	closedBy := make(map[int]string)
+
+	for _, c := range fc.Commits {
+		if num, ok := mergedPullNumber(c.GetCommit().GetMessage()); ok && !pulls[num] && mergedBy[num] == "" {
+			mergedBy[num] = c.GetSHA()
+			merged = append(merged, num)
+		}
+
+		for _, num := range closedIssueNumbers(c.GetCommit().GetMessage(), fc.Owner, fc.Repo) {
+			if !seen[num] && closedBy[num] == "" {
+				closedBy[num] = c.GetSHA()
+				closed = append(closed, num)
+			}
+		}
+	}
+
+	var decisions []Decision
+
+	for _, num := range merged {
+		pr, err := fc.Pulls.Get(ctx, num)
+		if errors.Is(err, ErrPullNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if pr.GetMerged() {
+			decisions = append(decisions, keep(pullRequestIssue(pr), "added, merged by commit %s", shortSHA(mergedBy[num])))
+		}
+	}
+
+	getter, ok := fc.Source.(IssueGetter)
+	if !ok {
+		return decisions, nil
+	}
+
+	for _, num := range closed {
+		issue, err := getter.GetIssue(ctx, fc.Owner, fc.Repo, num)
+		if errors.Is(err, ErrIssueNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !issue.IsPullRequest() && issue.GetState() == "closed" {
+			decisions = append(decisions, keep(issue, "added, closed by commit %s", shortSHA(closedBy[num])))
+		}
+	}
+
+	return decisions, nil
 }
 
-// FilterMergedPullsAfter filters out any issues or pull requests closed
-// outside of the commit comparison range.
-func FilterMergedPullsAfter(issues []*github.Issue, commits []github.RepositoryCommit) []*github.Issue {
-	r, _ := regexp.Compile(mergedPullRequestRegex)
+// unattributedCommits returns the SHAs of the commits that don't have a pull
+// request number in their messages, which are the commits the associated pull
+// requests need to be looked up for. Commits brought in by the merge commit of
+// a pull request are left out, as they are attributed to the merge commit, but
+// commits brought in by merging another branch are not, as the pull requests
+// that were merged into that branch are in the comparison too.
+func unattributedCommits(commits []github.RepositoryCommit) []string {
+	bySHA := make(map[string]*github.RepositoryCommit)
+
+	for i := range commits {
+		bySHA[commits[i].GetSHA()] = &commits[i]
+	}
 
-	var prs []int
+	// reachable returns the commits in the comparison reachable from the SHAs,
+	// without going past the commits to stop at
+	reachable := func(from []string, stop map[string]bool) map[string]bool {
+		reached := make(map[string]bool)
 
-	for _, issue := range issues {
-		if issue.IsPullRequest() {
-			prs = append(prs, *issue.Number)
+		for len(from) > 0 {
+			sha := from[len(from)-1]
+			from = from[:len(from)-1]
+
+			if bySHA[sha] == nil || reached[sha] || stop[sha] {
+				continue
+			}
+
+			reached[sha] = true
+
+			for _, parent := range bySHA[sha].Parents {
+				from = append(from, parent.GetSHA())
+			}
 		}
+
+		return reached
 	}
 
-	var merged []int
+	merged := make(map[string]bool)
 
 	for _, c := range commits {
-		matches := r.FindStringSubmatch(*c.Commit.Message)
-		if matches != nil {
-			var pr string
-
-			if matches[4] != "" {
-				pr = matches[4]
-			} else if matches[2] != "" && matches[3] != "" {
-				pr = matches[3]
-			} else if matches[1] != "" {
-				pr = matches[1]
-			}
+		if _, ok := mergedPullNumber(c.GetCommit().GetMessage()); !ok || len(c.Parents) < 2 {
+			continue
+		}
 
-			num, _ := strconv.Atoi(pr)
-			merged = append(merged, num)
+		var branch []string
+
+		for _, parent := range c.Parents[1:] {
+			branch = append(branch, parent.GetSHA())
+		}
+
+		for sha := range reachable(branch, reachable([]string{c.Parents[0].GetSHA()}, nil)) {
+			merged[sha] = true
 		}
 	}
 
-	var include []int
+	var shas []string
 
-	for _, id := range prs {
-		if contains(merged, id) {
-			include = append(include, id)
+	for _, c := range commits {
+		if _, ok := mergedPullNumber(c.GetCommit().GetMessage()); !ok && !merged[c.GetSHA()] {
+			shas = append(shas, c.GetSHA())
 		}
 	}
 
-	var filtered []*github.Issue
+	return shas
+}
+
+// ClosedByCommitsFilter returns a filter that drops the issues in the
+// repository of the context that were not closed by one of its commits.
+func ClosedByCommitsFilter() Filter {
+	return NewFilter("closed-by-commits", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return closedByCommits(issues, fc.Commits, fc.Owner, fc.Repo), nil
+	})
+}
+
+// FilterClosedByCommits filters out any issues in the repository that have not
+// been closed by commit messages.
+func FilterClosedByCommits(issues []*github.Issue, commits []github.RepositoryCommit, owner, repo string) []*github.Issue {
+	return kept(closedByCommits(issues, commits, owner, repo))
+}
+
+func closedByCommits(issues []*github.Issue, commits []github.RepositoryCommit, owner, repo string) []Decision {
+	closed := make(map[int]string)
+
+	for _, c := range commits {
+		for _, num := range closedIssueNumbers(*c.Commit.Message, owner, repo) {
+			closed[num] = c.GetSHA()
+		}
+	}
+
+	var decisions []Decision
 
 	for _, issue := range issues {
-		if contains(include, *issue.Number) || !issue.IsPullRequest() {
-			filtered = append(filtered, issue)
+		if issue.IsPullRequest() {
+			decisions = append(decisions, keep(issue, "pull request"))
+		} else if sha, ok := closed[*issue.Number]; ok {
+			decisions = append(decisions, keep(issue, "closed by commit %s", shortSHA(sha)))
+		} else {
+			decisions = append(decisions, drop(issue, "not closed by a commit in the comparison"))
 		}
 	}
 
-	return filtered
+	return decisions
+}
+
+// MergedPullsAfterFilter returns a filter that drops the pull requests that
+// were not merged by one of the commit messages of the context.
+func MergedPullsAfterFilter() Filter {
+	return NewFilter("merged-pulls-after", func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		return mergedPullsAfter(issues, fc.Commits), nil
+	})
+}
+
+// FilterMergedPullsAfter filters out any issues or pull requests closed
+// outside of the commit comparison range.
+func FilterMergedPullsAfter(issues []*github.Issue, commits []github.RepositoryCommit) []*github.Issue {
+	return kept(mergedPullsAfter(issues, commits))
+}
+
+func mergedPullsAfter(issues []*github.Issue, commits []github.RepositoryCommit) []Decision {
+	merged := make(map[int]string)
+
+	for _, c := range commits {
+		if num, ok := mergedPullNumber(*c.Commit.Message); ok {
+			merged[num] = c.GetSHA()
+		}
+	}
+
+	var decisions []Decision
+
+	for _, issue := range issues {
+		if !issue.IsPullRequest() {
+			decisions = append(decisions, keep(issue, "issue"))
+		} else if sha, ok := merged[*issue.Number]; ok {
+			decisions = append(decisions, keep(issue, "merged by commit %s", shortSHA(sha)))
+		} else {
+			decisions = append(decisions, drop(issue, "not merged by a commit in the comparison"))
+		}
+	}
+
+	return decisions
 }
 
 func contains(s []int, e int) bool {
@@ -197,3 +652,35 @@ func contains(s []int, e int) bool {
 
 	return false
 }
+
+// mergedPullNumber returns the number of the pull request (or GitLab merge
+// request) merged by the commit message, if it merges one.
+func mergedPullNumber(message string) (int, bool) {
+	mr, _ := regexp.Compile(mergedMergeRequestRegex)
+
+	if matches := mr.FindStringSubmatch(message); matches != nil {
+		num, _ := strconv.Atoi(matches[1])
+		return num, true
+	}
+
+	r, _ := regexp.Compile(mergedPullRequestRegex)
+
+	matches := r.FindStringSubmatch(message)
+	if matches == nil {
+		return 0, false
+	}
+
+	var pr string
+
+	if matches[4] != "" {
+		pr = matches[4]
+	} else if matches[2] != "" && matches[3] != "" {
+		pr = matches[3]
+	} else if matches[1] != "" {
+		pr = matches[1]
+	}
+
+	num, _ := strconv.Atoi(pr)
+
+	return num, true
+}
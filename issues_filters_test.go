@@ -0,0 +1,109 @@
+package releasekit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v18/github"
+)
+
+func TestUnattributedCommits(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []github.RepositoryCommit
+		want    []string
+	}{
+		{
+			name: "commits without pull requests",
+			commits: []github.RepositoryCommit{
+				testCommit("a", "Fix crash", "base"),
+				testCommit("b", "Fix crash (#1)", "a"),
+				testCommit("c", "Add endpoint", "b"),
+			},
+			want: []string{"a", "c"},
+		},
+		{
+			name: "commits merged by a pull request",
+			commits: []github.RepositoryCommit{
+				testCommit("f1", "Start feature", "base"),
+				testCommit("m1", "Fix typo", "base"),
+				testCommit("f2", "Merge branch 'master' into feature", "f1", "m1"),
+				testCommit("M", "Merge pull request #2 from o/feature", "m1", "f2"),
+			},
+			want: []string{"m1"},
+		},
+		{
+			name: "commits merged from another branch",
+			commits: []github.RepositoryCommit{
+				testCommit("m1", "Fix typo", "base"),
+				testCommit("d1", "Add endpoint", "base"),
+				testCommit("M", "Merge branch 'develop'", "m1", "d1"),
+			},
+			want: []string{"m1", "d1", "M"},
+		},
+		{
+			name: "commits without parents",
+			commits: []github.RepositoryCommit{
+				testCommit("a", "Fix crash"),
+				testCommit("M", "Merge pull request #2 from o/feature"),
+			},
+			want: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unattributedCommits(tt.commits); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unattributedCommits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterInComparisonMergedBranch(t *testing.T) {
+	source := &fakeSource{
+		pulls: map[int]*github.PullRequest{
+			20: testPull(20, "Add endpoint", "octocat", "d1"),
+			21: testPull(21, "Backport fix", "octocat", "b1"),
+		},
+		commits: map[string][]int{"d1": {20}, "m1": {21}},
+	}
+
+	// #20 was rebased onto develop, which was then merged into master
+	source.pulls[20].Base.Ref = github.String("develop")
+
+	commits := []github.RepositoryCommit{
+		testCommit("m1", "Fix typo", "base"),
+		testCommit("d1", "Add endpoint", "base"),
+		testCommit("M", "Merge branch 'develop'", "m1", "d1"),
+	}
+
+	issues := []*github.Issue{
+		pullRequestIssue(source.pulls[20]),
+		pullRequestIssue(source.pulls[21]),
+	}
+
+	fc := &FilterContext{
+		Source:  source,
+		Pulls:   NewPullRequestCache(source),
+		Owner:   "o",
+		Repo:    "r",
+		Head:    &commits[2],
+		Commits: commits,
+	}
+
+	decisions, err := inComparison(context.Background(), fc, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Decision{
+		keep(issues[0], "associated with commit d1"),
+		drop(issues[1], "not merged by or associated with a commit in the comparison"),
+	}
+
+	if !reflect.DeepEqual(decisions, want) {
+		t.Errorf("decisions = %+v, want %+v", decisions, want)
+	}
+}
@@ -0,0 +1,254 @@
+package releasekit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+const scpRemoteRegex = `^(?:[^@/]+@)?([^:/]+):(.+)$`
+
+// localCommitFormat is the git log format for commits, with the fields
+// separated by unit separators and commits separated by record separators.
+const localCommitFormat = "%H%x1f%P%x1f%an%x1f%ae%x1f%aI%x1f%B%x1e"
+
+// LocalProvider is a Provider that reads tags, commits and changed files from a
+// local clone of the repository using git, and uses another provider for the
+// issues, pull requests and releases. Comparisons are not limited in size, and
+// only need the tags to have been fetched.
+//
+// The pull requests associated with each commit are not looked up, so pull
+// requests are found from the commit messages alone. Use a PullRequestCache
+// for the remote provider to look them up using its API.
+type LocalProvider struct {
+	Provider
+
+	// Dir is the directory of the local clone.
+	Dir string
+
+	// RepositoryURL is the web URL of the repository, used to build the URLs
+	// of commits and comparisons.
+	RepositoryURL string
+
+	// PathPrefix is added to the repository URL before the paths of commits
+	// and comparisons, such as /- for GitLab.
+	PathPrefix string
+}
+
+// NewLocalProvider creates a new provider for the local clone in the
+// directory, using the remote provider for everything but tags and commits.
+// The repository URL is worked out from the URL of the origin remote, and the
+// path prefix from the remote provider.
+func NewLocalProvider(ctx context.Context, dir string, remote Provider) (*LocalProvider, error) {
+	p := &LocalProvider{Provider: remote, Dir: dir}
+
+	if _, ok := remote.(*GitLabProvider); ok {
+		p.PathPrefix = "/-"
+	}
+
+	origin, err := p.git(ctx, "remote", "get-url", "origin")
+	if err != nil {
+		return nil, err
+	}
+
+	p.RepositoryURL = repositoryURL(origin)
+
+	return p, nil
+}
+
+// GetCommitForTag gets the commit a tag is a reference to.
+func (p *LocalProvider) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	sha, err := p.git(ctx, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag+"^{commit}")
+	if err != nil {
+		return nil, &Error{Kind: ErrTagNotFound, Err: fmt.Errorf("no tag named %q in %s", tag, p.Dir)}
+	}
+
+	return p.commit(ctx, sha)
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+// Branches that only exist on the origin remote are found too.
+func (p *LocalProvider) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	sha, err := p.git(ctx, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		sha, err = p.git(ctx, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+ref+"^{commit}")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("no commit for %q in %s", ref, p.Dir)
+	}
+
+	return p.commit(ctx, sha)
+}
+
+// GetFirstCommit gets the first commit to the repository, which is the oldest
+// commit without parents reachable from HEAD.
+func (p *LocalProvider) GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error) {
+	out, err := p.git(ctx, "rev-list", "--max-parents=0", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	roots := strings.Fields(out)
+
+	return p.commit(ctx, roots[len(roots)-1])
+}
+
+// GetComparison gets the commit comparison for the given base and head range,
+// which is every commit reachable from the head but not the base, oldest
+// first, and the files changed since the merge base.
+func (p *LocalProvider) GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error) {
+	commits, err := p.log(ctx, "--reverse", base+".."+head)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := p.git(ctx, "diff", "--name-only", base+"..."+head)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &github.CommitsComparison{
+		TotalCommits: github.Int(len(commits)),
+		HTMLURL:      github.String(p.RepositoryURL + p.PathPrefix + "/compare/" + base + "..." + head),
+	}
+
+	for _, commit := range commits {
+		comparison.Commits = append(comparison.Commits, *commit)
+	}
+
+	for _, file := range strings.Split(files, "\n") {
+		if file != "" {
+			comparison.Files = append(comparison.Files, github.CommitFile{Filename: github.String(file)})
+		}
+	}
+
+	return comparison, nil
+}
+
+// ListTags lists the names of all the tags in the local clone.
+func (p *LocalProvider) ListTags(ctx context.Context) ([]string, error) {
+	out, err := p.git(ctx, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(out), nil
+}
+
+// TagExists checks if the tag exists in the local clone.
+func (p *LocalProvider) TagExists(ctx context.Context, tag string) (bool, error) {
+	_, err := p.git(ctx, "rev-parse", "--verify", "--quiet", "refs/tags/"+tag)
+	return err == nil, nil
+}
+
+// GetIssue gets the issue with the specified number in the repository using
+// the remote provider. If the remote provider can't get issues from other
+// repositories ErrIssueNotFound is returned.
+func (p *LocalProvider) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	if getter, ok := p.Provider.(IssueGetter); ok {
+		return getter.GetIssue(ctx, owner, repo, number)
+	}
+
+	return nil, &Error{Kind: ErrIssueNotFound, Err: fmt.Errorf("cannot get issue %s/%s#%d", owner, repo, number)}
+}
+
+// commit gets the commit with the SHA.
+func (p *LocalProvider) commit(ctx context.Context, sha string) (*github.RepositoryCommit, error) {
+	commits, err := p.log(ctx, "-1", sha)
+	if err != nil {
+		return nil, err
+	}
+
+	return commits[0], nil
+}
+
+// log lists the commits using git log with the arguments.
+func (p *LocalProvider) log(ctx context.Context, args ...string) ([]*github.RepositoryCommit, error) {
+	out, err := p.git(ctx, append([]string{"log", "--format=" + localCommitFormat}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*github.RepositoryCommit
+
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 6)
+		if len(fields) < 6 {
+			continue
+		}
+
+		date, _ := time.Parse(time.RFC3339, fields[4])
+
+		commit := &github.RepositoryCommit{
+			SHA:     github.String(fields[0]),
+			HTMLURL: github.String(p.RepositoryURL + p.PathPrefix + "/commit/" + fields[0]),
+			Commit: &github.Commit{
+				SHA:     github.String(fields[0]),
+				Message: github.String(strings.TrimSpace(fields[5])),
+				Author: &github.CommitAuthor{
+					Name:  github.String(fields[2]),
+					Email: github.String(fields[3]),
+					Date:  &date,
+				},
+			},
+		}
+
+		for _, parent := range strings.Fields(fields[1]) {
+			commit.Parents = append(commit.Parents, github.Commit{SHA: github.String(parent)})
+		}
+
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
+
+// git runs git in the directory with the arguments, returning the output
+// without the trailing newline.
+func (p *LocalProvider) git(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", p.Dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+
+		return "", fmt.Errorf("git %s: %s", args[0], err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// repositoryURL converts the URL of a git remote, such as
+// git@github.com:tombell/releasekit.git, to the web URL of the repository.
+func repositoryURL(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+
+	if strings.Contains(remote, "://") {
+		if u, err := url.Parse(remote); err == nil && u.Host != "" {
+			return "https://" + u.Hostname() + u.Path
+		}
+
+		return remote
+	}
+
+	r, _ := regexp.Compile(scpRemoteRegex)
+
+	if matches := r.FindStringSubmatch(remote); matches != nil {
+		return "https://" + matches[1] + "/" + strings.TrimPrefix(matches[2], "/")
+	}
+
+	return remote
+}
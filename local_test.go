@@ -0,0 +1,213 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRepository creates a git repository in a temporary directory with the
+// origin remote, and a commit for each message and tag pair, tagging it if the
+// tag isn't empty. The first branch points at the first commit. The directory
+// and SHAs of the commits are returned.
+func newTestRepository(t *testing.T, origin string, commits ...[2]string) (string, []string) {
+	dir, err := ioutil.TempDir("", "releasekit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Octo Cat", "-c", "user.email=octocat@example.com", "-c", "commit.gpgsign=false"}, args...)...)
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("git %s: %s", args[0], out)
+		}
+
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet")
+	git("remote", "add", "origin", origin)
+
+	var shas []string
+
+	for i, commit := range commits {
+		file := commit[0] + ".txt"
+		if err := ioutil.WriteFile(dir+"/"+file, []byte(commit[0]), 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+
+		git("add", file)
+		git("commit", "--quiet", "-m", commit[0])
+
+		if tag := commit[1]; tag != "" {
+			git("tag", tag)
+		}
+
+		shas = append(shas, git("rev-parse", "HEAD"))
+
+		if i == 0 {
+			git("branch", "first")
+		}
+	}
+
+	return dir, shas
+}
+
+func TestRepositoryURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:tombell/releasekit.git", "https://github.com/tombell/releasekit"},
+		{"github.com:tombell/releasekit", "https://github.com/tombell/releasekit"},
+		{"https://github.com/tombell/releasekit.git", "https://github.com/tombell/releasekit"},
+		{"https://token@github.com/tombell/releasekit/", "https://github.com/tombell/releasekit"},
+		{"ssh://git@gitlab.com:22/group/project.git", "https://gitlab.com/group/project"},
+		{"/srv/git/releasekit.git", "/srv/git/releasekit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			if got := repositoryURL(tt.remote); got != tt.want {
+				t.Errorf("repositoryURL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalProvider(t *testing.T) {
+	dir, shas := newTestRepository(t, "git@github.com:o/r.git",
+		[2]string{"first", "v1.0.0"},
+		[2]string{"second", ""},
+		[2]string{"third", "v1.1.0"},
+	)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+
+	p, err := NewLocalProvider(ctx, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "https://github.com/o/r"; p.RepositoryURL != want {
+		t.Errorf("repository URL = %s, want %s", p.RepositoryURL, want)
+	}
+
+	commit, err := p.GetCommitForTag(ctx, "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if commit.GetSHA() != shas[2] || commit.GetCommit().GetMessage() != "third" || commit.GetCommit().GetAuthor().GetName() != "Octo Cat" {
+		t.Errorf("commit = %v, want the third commit", commit)
+	}
+
+	if want := "https://github.com/o/r/commit/" + shas[2]; commit.GetHTMLURL() != want {
+		t.Errorf("commit URL = %s, want %s", commit.GetHTMLURL(), want)
+	}
+
+	if _, err := p.GetCommitForTag(ctx, "v2.0.0"); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("got error %v, want ErrTagNotFound", err)
+	}
+
+	if commit, err := p.GetCommit(ctx, "first"); err != nil || commit.GetSHA() != shas[0] {
+		t.Errorf("branch commit = %v (%v), want the first commit", commit, err)
+	}
+
+	if commit, err := p.GetFirstCommit(ctx); err != nil || commit.GetSHA() != shas[0] {
+		t.Errorf("first commit = %v (%v), want the first commit", commit, err)
+	}
+
+	tags, err := p.ListTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"v1.0.0", "v1.1.0"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	if ok, _ := p.TagExists(ctx, "v1.0.0"); !ok {
+		t.Error("v1.0.0 doesn't exist, want it to")
+	}
+
+	if ok, _ := p.TagExists(ctx, "v2.0.0"); ok {
+		t.Error("v2.0.0 exists, want it not to")
+	}
+
+	comparison, err := p.GetComparison(ctx, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commits []string
+
+	for _, c := range comparison.Commits {
+		commits = append(commits, c.GetSHA())
+	}
+
+	if want := shas[1:]; !reflect.DeepEqual(commits, want) {
+		t.Errorf("commits = %v, want %v", commits, want)
+	}
+
+	if got := comparison.Commits[1].Parents[0].GetSHA(); got != shas[1] {
+		t.Errorf("parent = %s, want %s", got, shas[1])
+	}
+
+	var files []string
+
+	for _, f := range comparison.Files {
+		files = append(files, f.GetFilename())
+	}
+
+	if want := []string{"second.txt", "third.txt"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+
+	if want := "https://github.com/o/r/compare/v1.0.0...v1.1.0"; comparison.GetHTMLURL() != want {
+		t.Errorf("comparison URL = %s, want %s", comparison.GetHTMLURL(), want)
+	}
+}
+
+func TestLocalProviderGitLabURLs(t *testing.T) {
+	dir, shas := newTestRepository(t, "git@gitlab.com:group/project.git",
+		[2]string{"first", "v1.0.0"},
+		[2]string{"second", "v1.1.0"},
+	)
+	defer os.RemoveAll(dir)
+
+	remote, err := NewGitLabProvider("", "token", nil, "group", "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	p, err := NewLocalProvider(ctx, dir, remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparison, err := p.GetComparison(ctx, "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "https://gitlab.com/group/project/-/compare/v1.0.0...v1.1.0"; comparison.GetHTMLURL() != want {
+		t.Errorf("comparison URL = %s, want %s", comparison.GetHTMLURL(), want)
+	}
+
+	if want := "https://gitlab.com/group/project/-/commit/" + shas[1]; comparison.Commits[0].GetHTMLURL() != want {
+		t.Errorf("commit URL = %s, want %s", comparison.Commits[0].GetHTMLURL(), want)
+	}
+}
@@ -0,0 +1,363 @@
+package releasekit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// DefaultTemplate is the template used to render the release notes when no
+// custom template is given.
+const DefaultTemplate = `{{- define "item" -}}
+{{ if .SHA -}}
+* {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Title }}
+{{- if .Number }} ([{{ .Ref }}]({{ .URL }})){{ end }} ([{{ .ShortSHA }}]({{ .CommitURL }}))
+{{- else -}}
+* [{{ .Ref }}]({{ .URL }}) - {{ .Title }}
+{{- if .Fixes }} (fixes {{ range $i, $issue := .Fixes }}{{ if $i }}, {{ end }}[{{ .Ref }}]({{ .URL }}){{ end }}){{ end }}
+{{- end }}
+{{- if .Highlights }} {{ range $i, $label := .Highlights }}{{ if $i }}, {{ end }}**{{ $label }}**{{ end }}{{ end }} (@{{ .Author }})
+{{ if .BreakingChange }}  * {{ .BreakingChange }}
+{{ end -}}
+{{ range .FixedBy }}  * [{{ .Ref }}]({{ .URL }}) - {{ .Title }} (@{{ .Author }})
+{{ end -}}
+{{ end -}}
+
+{{- if not .Items -}}
+New Release
+{{- else -}}
+{{ if .Sections -}}
+{{ range $i, $section := .Sections -}}
+{{ if $i }}
+{{ end -}}
+## {{ .Title }}
+{{ range .Items }}{{ template "item" . }}{{ end -}}
+{{ end -}}
+{{ else -}}
+## Changes
+{{ range .Items }}{{ template "item" . }}{{ end -}}
+{{ end -}}
+{{ if .Changed }}
+### Watched File Changes
+Changes: {{ .CompareURL }}
+{{ range .Changed -}}
+* {{ . }}
+{{ end -}}
+{{ end -}}
+{{ end -}}`
+
+// Notes is the structured model of a release that is passed to the release
+// notes template, and output as JSON.
+type Notes struct {
+	Previous       string    `json:"previous"`
+	Next           string    `json:"next"`
+	PreviousCommit string    `json:"previous_commit,omitempty"`
+	NextCommit     string    `json:"next_commit,omitempty"`
+	CompareURL     string    `json:"compare_url"`
+	Date           time.Time `json:"date"`
+
+	Items    []*Item    `json:"items"`
+	Sections []*Section `json:"-"`
+	Labels   []string   `json:"labels,omitempty"`
+	Changed  []string   `json:"changed,omitempty"`
+
+	ReleaseURL string `json:"release_url,omitempty"`
+}
+
+// Item is a single issue, pull request or commit included in the release
+// notes. Items built from conventional commits have the commit fields set, and
+// the pull request fields if the commit message references one.
+type Item struct {
+	Number      int      `json:"number,omitempty"`
+	Title       string   `json:"title"`
+	URL         string   `json:"url,omitempty"`
+	Author      string   `json:"author"`
+	Labels      []string `json:"labels,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	PullRequest bool     `json:"pull_request"`
+	Section     string   `json:"section,omitempty"`
+	Repository  string   `json:"repository,omitempty"`
+	MergeCommit string   `json:"merge_commit,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	Fixes       []*Item  `json:"fixes,omitempty"`
+	FixedBy     []*Item  `json:"fixed_by,omitempty"`
+
+	SHA            string `json:"sha,omitempty"`
+	CommitURL      string `json:"commit_url,omitempty"`
+	Type           string `json:"type,omitempty"`
+	Scope          string `json:"scope,omitempty"`
+	Breaking       bool   `json:"breaking,omitempty"`
+	BreakingChange string `json:"breaking_change,omitempty"`
+}
+
+// NewItem creates a release notes item from the issue, highlighting any of the
+// given labels the issue has.
+func NewItem(issue *github.Issue, highlight []string) *Item {
+	item := &Item{
+		Number:      issue.GetNumber(),
+		Title:       issue.GetTitle(),
+		URL:         issue.GetHTMLURL(),
+		Author:      issue.GetUser().GetLogin(),
+		PullRequest: issue.IsPullRequest(),
+	}
+
+	for _, l := range issue.Labels {
+		item.Labels = append(item.Labels, l.GetName())
+	}
+
+	item.highlight(highlight)
+
+	return item
+}
+
+// highlight adds any of the given labels the item has to its highlights.
+func (i *Item) highlight(labels []string) {
+	for _, label := range labels {
+		if i.HasLabel(label) {
+			i.Highlights = append(i.Highlights, label)
+		}
+	}
+}
+
+// Ref returns the reference to the item's issue or pull request, as #N, or
+// owner/repo#N if it is in another repository.
+func (i *Item) Ref() string {
+	if i.Repository != "" {
+		return fmt.Sprintf("%s#%d", i.Repository, i.Number)
+	}
+
+	return fmt.Sprintf("#%d", i.Number)
+}
+
+// ShortSHA returns the abbreviated SHA of the item's commit.
+func (i *Item) ShortSHA() string {
+	return shortSHA(i.SHA)
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+// NestClosedIssues nests the issues closed by each pull request item under it
+// as its Fixes, using the closing references in the pull requests. The issues
+// are all those fetched or added by the filters for the release, including the
+// ones filtered out for being closed by a pull request, and any in other
+// repositories. Issue items that are nested are removed from the items, which
+// are returned.
+func NestClosedIssues(items []*Item, issues []*github.Issue, highlight []string, owner, repo string) []*Item {
+	bodies := make(map[int]string)
+	closed := make(map[string]*github.Issue)
+
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			bodies[issue.GetNumber()] = issue.GetBody()
+			continue
+		}
+
+		ref := Reference{Number: issue.GetNumber()}
+
+		if r := issue.GetRepository(); r != nil && !strings.EqualFold(r.GetFullName(), owner+"/"+repo) {
+			ref.Owner, ref.Repo = r.GetOwner().GetLogin(), r.GetName()
+		}
+
+		closed[strings.ToLower(ref.String())] = issue
+	}
+
+	nested := make(map[string]bool)
+
+	for _, item := range items {
+		if !item.PullRequest || item.Repository != "" {
+			continue
+		}
+
+		for _, ref := range ParseClosingReferences(bodies[item.Number]) {
+			if ref.In(owner, repo) {
+				ref.Owner, ref.Repo = "", ""
+			}
+
+			issue, ok := closed[strings.ToLower(ref.String())]
+			if !ok {
+				continue
+			}
+
+			fixed := NewItem(issue, highlight)
+			if ref.Owner != "" {
+				fixed.Repository = ref.Owner + "/" + ref.Repo
+			}
+
+			fixed.Reason = fmt.Sprintf("issue closed by pull request %s", item.Ref())
+
+			item.Fixes = append(item.Fixes, fixed)
+			nested[strings.ToLower(fixed.Ref())] = true
+		}
+	}
+
+	var remaining []*Item
+
+	for _, item := range items {
+		if item.PullRequest || item.SHA != "" || !nested[strings.ToLower(item.Ref())] {
+			remaining = append(remaining, item)
+		}
+	}
+
+	return remaining
+}
+
+// IssuesFirst flips the nesting of the items, so the issues closed by pull
+// requests are listed instead of the pull requests, with the pull requests that
+// closed them nested under them as FixedBy. An issue closed by more than one
+// pull request is listed once, where the first of them was.
+func IssuesFirst(items []*Item) []*Item {
+	var flipped []*Item
+
+	issues := make(map[string]*Item)
+
+	for _, item := range items {
+		if len(item.Fixes) == 0 {
+			flipped = append(flipped, item)
+			continue
+		}
+
+		pr := *item
+		pr.Fixes = nil
+
+		for _, fixed := range item.Fixes {
+			issue, ok := issues[strings.ToLower(fixed.Ref())]
+			if !ok {
+				copied := *fixed
+				issue = &copied
+				issues[strings.ToLower(fixed.Ref())] = issue
+				flipped = append(flipped, issue)
+			}
+
+			issue.FixedBy = append(issue.FixedBy, &pr)
+		}
+	}
+
+	return flipped
+}
+
+// AnnotateItems sets the merge commit of the pull request items, and the reason
+// each item was included in the release, using the commits in the comparison
+// of the repository.
+func AnnotateItems(items []*Item, commits []github.RepositoryCommit, owner, repo string) {
+	merged := make(map[int]string)
+	closed := make(map[int]string)
+
+	for _, c := range commits {
+		if num, ok := mergedPullNumber(c.GetCommit().GetMessage()); ok {
+			merged[num] = c.GetSHA()
+		}
+
+		for _, num := range closedIssueNumbers(c.GetCommit().GetMessage(), owner, repo) {
+			closed[num] = c.GetSHA()
+		}
+	}
+
+	for _, item := range items {
+		switch {
+		case item.SHA != "":
+			item.Reason = fmt.Sprintf("conventional commit %s", item.ShortSHA())
+		case item.Repository != "":
+			item.Reason = fmt.Sprintf("issue in %s closed between the tags", item.Repository)
+		case item.PullRequest && merged[item.Number] != "":
+			item.MergeCommit = merged[item.Number]
+			item.Reason = fmt.Sprintf("pull request merged in commit %s", shortSHA(item.MergeCommit))
+		case item.PullRequest:
+			item.Reason = "pull request merged between the tags"
+		case closed[item.Number] != "":
+			item.Reason = fmt.Sprintf("issue closed by commit %s", shortSHA(closed[item.Number]))
+		default:
+			item.Reason = "issue closed between the tags"
+		}
+	}
+}
+
+// HasLabel returns true if the item has the given label.
+func (i *Item) HasLabel(label string) bool {
+	for _, l := range i.Labels {
+		if l == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Issues returns the items that are issues.
+func (n *Notes) Issues() []*Item {
+	var issues []*Item
+
+	for _, item := range n.Items {
+		if !item.PullRequest {
+			issues = append(issues, item)
+		}
+	}
+
+	return issues
+}
+
+// PullRequests returns the items that are pull requests.
+func (n *Notes) PullRequests() []*Item {
+	var pulls []*Item
+
+	for _, item := range n.Items {
+		if item.PullRequest {
+			pulls = append(pulls, item)
+		}
+	}
+
+	return pulls
+}
+
+// Authors returns the unique authors of the items, in the order they first
+// appear.
+func (n *Notes) Authors() []string {
+	var authors []string
+
+	seen := make(map[string]bool)
+
+	for _, item := range n.Items {
+		if item.Author == "" || seen[item.Author] {
+			continue
+		}
+
+		seen[item.Author] = true
+		authors = append(authors, item.Author)
+	}
+
+	return authors
+}
+
+// templateFuncs are the additional functions available to release notes
+// templates.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// RenderNotes renders the release notes using the given template text.
+func RenderNotes(text string, notes *Notes) (string, error) {
+	tmpl, err := template.New("notes").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, notes); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
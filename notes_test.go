@@ -0,0 +1,252 @@
+package releasekit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v18/github"
+)
+
+func TestRenderNotesDefaultTemplate(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes *Notes
+		want  string
+	}{
+		{
+			name:  "no items",
+			notes: &Notes{},
+			want:  "New Release",
+		},
+		{
+			name: "items",
+			notes: &Notes{
+				Items: []*Item{
+					{Number: 1, Title: "Fix crash", URL: "https://github.com/o/r/pull/1", Author: "octocat", PullRequest: true},
+					{Number: 2, Title: "Crash on start", URL: "https://github.com/o/r/issues/2", Author: "hubot", Highlights: []string{"bug", "urgent"}},
+				},
+			},
+			want: "## Changes\n" +
+				"* [#1](https://github.com/o/r/pull/1) - Fix crash (@octocat)\n" +
+				"* [#2](https://github.com/o/r/issues/2) - Crash on start **bug**, **urgent** (@hubot)\n",
+		},
+		{
+			name: "watched files",
+			notes: &Notes{
+				CompareURL: "https://github.com/o/r/compare/v1.0.0...v1.1.0",
+				Items: []*Item{
+					{Number: 1, Title: "Fix crash", URL: "https://github.com/o/r/pull/1", Author: "octocat", PullRequest: true},
+				},
+				Changed: []string{"go.mod", "go.sum"},
+			},
+			want: "## Changes\n" +
+				"* [#1](https://github.com/o/r/pull/1) - Fix crash (@octocat)\n" +
+				"\n" +
+				"### Watched File Changes\n" +
+				"Changes: https://github.com/o/r/compare/v1.0.0...v1.1.0\n" +
+				"* go.mod\n" +
+				"* go.sum\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderNotes(DefaultTemplate, tt.notes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("RenderNotes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// nesting describes the items as their references, with the references of the
+// items nested under them in brackets.
+func nesting(items []*Item) []string {
+	var refs []string
+
+	for _, item := range items {
+		ref := item.Ref()
+
+		var nested []string
+
+		for _, fixed := range item.Fixes {
+			nested = append(nested, fixed.Ref())
+		}
+
+		for _, pr := range item.FixedBy {
+			nested = append(nested, pr.Ref())
+		}
+
+		if len(nested) > 0 {
+			ref += " [" + strings.Join(nested, " ") + "]"
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
+
+func TestNestClosedIssues(t *testing.T) {
+	issue := func(number int, repository string) *github.Issue {
+		issue := &github.Issue{Number: github.Int(number), Title: github.String("Issue")}
+
+		if repository != "" {
+			parts := strings.SplitN(repository, "/", 2)
+			issue.Repository = &github.Repository{
+				FullName: github.String(repository),
+				Owner:    &github.User{Login: github.String(parts[0])},
+				Name:     github.String(parts[1]),
+			}
+		}
+
+		return issue
+	}
+
+	pull := func(number int, body string) *github.Issue {
+		pr := testPull(number, "Pull request", "octocat", "m1")
+		pr.Body = github.String(body)
+
+		return pullRequestIssue(pr)
+	}
+
+	tests := []struct {
+		name   string
+		items  []*Item
+		issues []*github.Issue
+		want   []string
+	}{
+		{
+			name:   "issues closed by a pull request",
+			items:  []*Item{{Number: 1, PullRequest: true}, {Number: 2}, {Number: 3}},
+			issues: []*github.Issue{pull(1, "Fixes #2 and closes o/r#3"), issue(2, ""), issue(3, "o/r")},
+			want:   []string{"#1 [#2 #3]"},
+		},
+		{
+			name:   "issue in another repository",
+			items:  []*Item{{Number: 1, PullRequest: true}, {Number: 5, Repository: "other/repo"}},
+			issues: []*github.Issue{pull(1, "Closes other/repo#5"), issue(5, "other/repo")},
+			want:   []string{"#1 [other/repo#5]"},
+		},
+		{
+			name:   "issue filtered out",
+			items:  []*Item{{Number: 1, PullRequest: true}},
+			issues: []*github.Issue{pull(1, "Fixes #2"), issue(2, "")},
+			want:   []string{"#1 [#2]"},
+		},
+		{
+			name:   "issue not fetched",
+			items:  []*Item{{Number: 1, PullRequest: true}, {Number: 3}},
+			issues: []*github.Issue{pull(1, "Fixes #2"), issue(3, "")},
+			want:   []string{"#1", "#3"},
+		},
+		{
+			name:   "mentioned issue",
+			items:  []*Item{{Number: 1, PullRequest: true}, {Number: 2}},
+			issues: []*github.Issue{pull(1, "Related to #2"), issue(2, "")},
+			want:   []string{"#1", "#2"},
+		},
+		{
+			name:   "conventional commit item",
+			items:  []*Item{{Number: 1, PullRequest: true}, {Number: 2, SHA: "c1"}},
+			issues: []*github.Issue{pull(1, "Fixes #2"), issue(2, "")},
+			want:   []string{"#1 [#2]", "#2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := NestClosedIssues(tt.items, tt.issues, nil, "o", "r")
+
+			if got := nesting(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NestClosedIssues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNestClosedIssuesReason(t *testing.T) {
+	pr := testPull(1, "Fix crash", "octocat", "m1")
+	pr.Body = github.String("Fixes #2")
+
+	issues := []*github.Issue{
+		pullRequestIssue(pr),
+		{Number: github.Int(2), Title: github.String("Crash"), Labels: []github.Label{{Name: github.String("bug")}}},
+	}
+
+	items := NestClosedIssues([]*Item{{Number: 1, PullRequest: true}}, issues, []string{"bug"}, "o", "r")
+
+	fixed := items[0].Fixes[0]
+
+	if want := "issue closed by pull request #1"; fixed.Reason != want {
+		t.Errorf("reason = %q, want %q", fixed.Reason, want)
+	}
+
+	if want := []string{"bug"}; !reflect.DeepEqual(fixed.Highlights, want) {
+		t.Errorf("highlights = %v, want %v", fixed.Highlights, want)
+	}
+}
+
+func TestIssuesFirst(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []*Item
+		want  []string
+	}{
+		{
+			name: "no nested issues",
+			items: []*Item{
+				{Number: 1, PullRequest: true},
+				{Number: 2},
+			},
+			want: []string{"#1", "#2"},
+		},
+		{
+			name: "nested issues",
+			items: []*Item{
+				{Number: 1, PullRequest: true, Fixes: []*Item{{Number: 2}, {Number: 3}}},
+				{Number: 4},
+			},
+			want: []string{"#2 [#1]", "#3 [#1]", "#4"},
+		},
+		{
+			name: "issue closed by more than one pull request",
+			items: []*Item{
+				{Number: 5},
+				{Number: 1, PullRequest: true, Fixes: []*Item{{Number: 2}}},
+				{Number: 4, PullRequest: true, Fixes: []*Item{{Number: 3}, {Number: 2}}},
+			},
+			want: []string{"#5", "#2 [#1 #4]", "#3 [#4]"},
+		},
+		{
+			name: "issue in another repository",
+			items: []*Item{
+				{Number: 1, PullRequest: true, Fixes: []*Item{{Number: 2, Repository: "other/repo"}, {Number: 2}}},
+			},
+			want: []string{"other/repo#2 [#1]", "#2 [#1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flipped := IssuesFirst(tt.items)
+
+			if got := nesting(flipped); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IssuesFirst() = %v, want %v", got, tt.want)
+			}
+
+			for _, issue := range flipped {
+				for _, pr := range issue.FixedBy {
+					if len(pr.Fixes) > 0 {
+						t.Errorf("%s is nested under %s with its fixes", pr.Ref(), issue.Ref())
+					}
+				}
+			}
+		})
+	}
+}
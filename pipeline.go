@@ -0,0 +1,328 @@
+package releasekit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// FilterContext is the context shared by the filters in a pipeline, with
+// everything they need to know about the release.
+type FilterContext struct {
+	// Source is the source of the issues and pull requests.
+	Source Source
+
+	// Pulls is the cache of pull requests, shared so each pull request is only
+	// fetched once by all the filters.
+	Pulls *PullRequestCache
+
+	// Owner and Repo are the repository of the release.
+	Owner string
+	Repo  string
+
+	// Base and Head are the commits of the previous and next release tags.
+	Base *github.RepositoryCommit
+	Head *github.RepositoryCommit
+
+	// Since is the earliest time an issue can have been closed to be in the
+	// release. It is zero for the first release.
+	Since time.Time
+
+	// Commits are the commits in the comparison between the base and head.
+	Commits []github.RepositoryCommit
+}
+
+// Decision is the decision of a filter to keep or drop an issue or pull
+// request, and why.
+type Decision struct {
+	Issue  *github.Issue
+	Filter string
+	Keep   bool
+	Reason string
+}
+
+// keep returns a decision to keep the issue for the reason.
+func keep(issue *github.Issue, format string, args ...interface{}) Decision {
+	return Decision{Issue: issue, Keep: true, Reason: fmt.Sprintf(format, args...)}
+}
+
+// drop returns a decision to drop the issue for the reason.
+func drop(issue *github.Issue, format string, args ...interface{}) Decision {
+	return Decision{Issue: issue, Reason: fmt.Sprintf(format, args...)}
+}
+
+// kept returns the issues the decisions keep, in order.
+func kept(decisions []Decision) []*github.Issue {
+	var issues []*github.Issue
+
+	for _, d := range decisions {
+		if d.Keep {
+			issues = append(issues, d.Issue)
+		}
+	}
+
+	return issues
+}
+
+// Filter decides which issues and pull requests to keep in the release. It
+// returns a decision for each of the issues, and can add issues by returning
+// decisions to keep issues that were not given to it.
+type Filter interface {
+	// Name is the name of the filter, used in the decisions.
+	Name() string
+
+	// Filter returns the decisions for the issues.
+	Filter(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error)
+}
+
+type filterFunc struct {
+	name string
+	fn   func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error)
+}
+
+func (f *filterFunc) Name() string {
+	return f.name
+}
+
+func (f *filterFunc) Filter(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+	return f.fn(ctx, fc, issues)
+}
+
+// NewFilter creates a filter with the name that uses the function to decide
+// which issues and pull requests to keep.
+func NewFilter(name string, fn func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error)) Filter {
+	return &filterFunc{name: name, fn: fn}
+}
+
+// KeepFunc creates a filter with the name that decides whether to keep each
+// issue or pull request on its own, using the function to return whether to
+// keep it and why.
+func KeepFunc(name string, fn func(fc *FilterContext, issue *github.Issue) (bool, string)) Filter {
+	return NewFilter(name, func(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]Decision, error) {
+		var decisions []Decision
+
+		for _, issue := range issues {
+			ok, reason := fn(fc, issue)
+			decisions = append(decisions, Decision{Issue: issue, Keep: ok, Reason: reason})
+		}
+
+		return decisions, nil
+	})
+}
+
+// Pipeline is a list of filters, each applied in order to the issues and pull
+// requests kept by the filter before it. Filters can be added, removed and
+// reordered by changing the list.
+type Pipeline struct {
+	Filters []Filter
+}
+
+// NewPipeline creates a new pipeline with the filters.
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{Filters: filters}
+}
+
+// DefaultPipeline creates the pipeline used by the command line tool. Issues
+// and pull requests excluded by the rules are dropped, then if the context has
+// commits the issues closed by a pull request are dropped and only the ones in
+// the comparison are kept, adding any the commits merged or closed that are
+// missing. Otherwise the ones closed before the previous tag, closed by a pull
+// request or closed after the next tag, and any pull requests that weren't
+// merged, are dropped.
+func DefaultPipeline(fc *FilterContext, rules *ExclusionRules) *Pipeline {
+	p := NewPipeline(ExcludedFilter(rules))
+
+	if len(fc.Commits) > 0 {
+		p.Add(ClosedByPullFilter(), InComparisonFilter())
+	} else {
+		p.Add(ClosedBeforeFilter(), ClosedByPullFilter(), ClosedAfterFilter(), NonMergedPullsFilter())
+	}
+
+	return p
+}
+
+// Add adds the filters to the end of the pipeline.
+func (p *Pipeline) Add(filters ...Filter) {
+	p.Filters = append(p.Filters, filters...)
+}
+
+// Run runs the filters on the issues and pull requests, returning the ones kept
+// by every filter and all the decisions made, in the order they were made.
+//
+// Issues added by a filter are run through the filters before it first, so
+// they are only added if those filters keep them, such as ones excluded by
+// the rules. Their decisions are made before the decision to add them. Issues
+// that an earlier filter dropped are never added back. The first error stops
+// the pipeline and is returned.
+func (p *Pipeline) Run(ctx context.Context, fc *FilterContext, issues []*github.Issue) ([]*github.Issue, []Decision, error) {
+	var all []Decision
+
+	dropped := make(map[issueKey]bool)
+
+	for i, f := range p.Filters {
+		given := make(map[*github.Issue]bool)
+		keys := make(map[issueKey]bool)
+
+		for _, issue := range issues {
+			given[issue] = true
+			keys[keyOf(issue)] = true
+		}
+
+		decisions, err := f.Filter(ctx, fc, issues)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var made, added []Decision
+		var adding []*github.Issue
+
+		for _, d := range decisions {
+			d.Filter = f.Name()
+
+			switch {
+			case given[d.Issue]:
+				made = append(made, d)
+			case dropped[keyOf(d.Issue)] || keys[keyOf(d.Issue)]:
+				// already dropped by an earlier filter, or given to the
+				// filter as another issue
+			default:
+				keys[keyOf(d.Issue)] = true
+				added = append(added, d)
+
+				if d.Keep {
+					adding = append(adding, d.Issue)
+				}
+			}
+		}
+
+		if len(adding) > 0 && i > 0 {
+			passed, earlier, err := NewPipeline(p.Filters[:i]...).Run(ctx, fc, adding)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			all = append(all, earlier...)
+
+			survived := make(map[*github.Issue]bool)
+
+			for _, issue := range passed {
+				survived[issue] = true
+			}
+
+			for _, d := range earlier {
+				if !d.Keep {
+					dropped[keyOf(d.Issue)] = true
+				}
+			}
+
+			var survivors []Decision
+
+			for _, d := range added {
+				if !d.Keep || survived[d.Issue] {
+					survivors = append(survivors, d)
+				}
+			}
+
+			added = survivors
+		}
+
+		made = append(made, added...)
+
+		for _, d := range made {
+			if !d.Keep {
+				dropped[keyOf(d.Issue)] = true
+			}
+		}
+
+		all = append(all, made...)
+		issues = kept(made)
+	}
+
+	return issues, all, nil
+}
+
+// issueKey identifies an issue or pull request, which can be numbered
+// separately on GitLab.
+type issueKey struct {
+	pull   bool
+	number int
+}
+
+func keyOf(issue *github.Issue) issueKey {
+	return issueKey{pull: issue.IsPullRequest(), number: issue.GetNumber()}
+}
+
+// Explanation is a report of why each issue and pull request was included in
+// or excluded from the release.
+type Explanation struct {
+	Candidates []*Candidate `json:"candidates"`
+}
+
+// Candidate is an issue or pull request that was considered for the release,
+// with the decision of each filter that saw it. Filters after the one that
+// dropped it do not see it.
+type Candidate struct {
+	Number      int                 `json:"number"`
+	Title       string              `json:"title"`
+	URL         string              `json:"url,omitempty"`
+	PullRequest bool                `json:"pull_request"`
+	Included    bool                `json:"included"`
+	Decisions   []CandidateDecision `json:"decisions"`
+}
+
+// CandidateDecision is the decision of a filter to keep or drop a candidate.
+type CandidateDecision struct {
+	Filter string `json:"filter"`
+	Keep   bool   `json:"keep"`
+	Reason string `json:"reason"`
+}
+
+// Explain creates the explanation for the candidates given to a pipeline, from
+// the decisions it made. Issues added by the filters are included after the
+// candidates.
+func Explain(candidates []*github.Issue, decisions []Decision) *Explanation {
+	e := &Explanation{Candidates: []*Candidate{}}
+
+	byIssue := make(map[*github.Issue]*Candidate)
+
+	add := func(issue *github.Issue) *Candidate {
+		c := &Candidate{
+			Number:      issue.GetNumber(),
+			Title:       issue.GetTitle(),
+			URL:         issue.GetHTMLURL(),
+			PullRequest: issue.IsPullRequest(),
+		}
+
+		byIssue[issue] = c
+		e.Candidates = append(e.Candidates, c)
+
+		return c
+	}
+
+	for _, issue := range candidates {
+		add(issue)
+	}
+
+	for _, d := range decisions {
+		c, ok := byIssue[d.Issue]
+		if !ok {
+			c = add(d.Issue)
+		}
+
+		c.Decisions = append(c.Decisions, CandidateDecision{Filter: d.Filter, Keep: d.Keep, Reason: d.Reason})
+	}
+
+	for _, c := range e.Candidates {
+		c.Included = len(c.Decisions) > 0
+
+		for _, d := range c.Decisions {
+			if !d.Keep {
+				c.Included = false
+			}
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,165 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// fakeSource is a Source and PullRequestLister with the pull requests given,
+// which fails the test if anything else is fetched.
+type fakeSource struct {
+	pulls   map[int]*github.PullRequest
+	commits map[string][]int
+}
+
+func (s *fakeSource) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	return nil, errors.New("unexpected GetCommitForTag")
+}
+
+func (s *fakeSource) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	return nil, errors.New("unexpected GetCommit")
+}
+
+func (s *fakeSource) GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error) {
+	return nil, errors.New("unexpected GetFirstCommit")
+}
+
+func (s *fakeSource) GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error) {
+	return nil, errors.New("unexpected GetComparison")
+}
+
+func (s *fakeSource) FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error) {
+	return nil, errors.New("unexpected FetchClosedIssuesSince")
+}
+
+func (s *fakeSource) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	if pr, ok := s.pulls[number]; ok {
+		return pr, nil
+	}
+
+	return nil, &Error{Kind: ErrPullNotFound, Err: fmt.Errorf("no pull request #%d", number)}
+}
+
+func (s *fakeSource) ListPullRequestsForCommits(ctx context.Context, shas []string) (map[string][]*github.PullRequest, error) {
+	pulls := make(map[string][]*github.PullRequest)
+
+	for _, sha := range shas {
+		for _, number := range s.commits[sha] {
+			pulls[sha] = append(pulls[sha], s.pulls[number])
+		}
+	}
+
+	return pulls, nil
+}
+
+func testPull(number int, title, login, sha string) *github.PullRequest {
+	return &github.PullRequest{
+		Number:         github.Int(number),
+		Title:          github.String(title),
+		User:           &github.User{Login: github.String(login)},
+		Merged:         github.Bool(true),
+		MergeCommitSHA: github.String(sha),
+		Base:           &github.PullRequestBranch{Ref: github.String("master")},
+	}
+}
+
+func testCommit(sha, message string, parents ...string) github.RepositoryCommit {
+	c := github.RepositoryCommit{
+		SHA:    github.String(sha),
+		Commit: &github.Commit{Message: github.String(message)},
+	}
+
+	for _, p := range parents {
+		c.Parents = append(c.Parents, github.Commit{SHA: github.String(p)})
+	}
+
+	return c
+}
+
+func TestPipelineExcludesAddedPullRequests(t *testing.T) {
+	source := &fakeSource{
+		pulls: map[int]*github.PullRequest{
+			14: testPull(14, "Fix crash on startup", "octocat", "c3"),
+			15: testPull(15, "Bump dependencies", "dependabot[bot]", "c1"),
+			16: testPull(16, "Bump more dependencies", "dependabot[bot]", "c2"),
+			17: testPull(17, "Add endpoint", "octocat", "c4"),
+		},
+		commits: map[string][]int{"c1": {15}, "c2": {16}, "c4": {17}},
+	}
+
+	commits := []github.RepositoryCommit{
+		testCommit("c1", "Bump dependencies", "c0"),
+		testCommit("c2", "Bump more dependencies", "c1"),
+		testCommit("c3", "Fix crash on startup (#14)", "c2"),
+		testCommit("c4", "Add endpoint", "c3"),
+	}
+
+	// #16 and #17 were closed before the previous release was tagged, so are
+	// only found through their commits
+	issues := []*github.Issue{
+		pullRequestIssue(source.pulls[14]),
+		pullRequestIssue(source.pulls[15]),
+	}
+
+	fc := &FilterContext{
+		Source:  source,
+		Pulls:   NewPullRequestCache(source),
+		Owner:   "tombell",
+		Repo:    "releasekit",
+		Head:    &commits[3],
+		Commits: commits,
+	}
+
+	rules := &ExclusionRules{Authors: []string{"dependabot"}, Exclude: []int{15}}
+
+	kept, decisions, err := DefaultPipeline(fc, rules).Run(context.Background(), fc, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var numbers []int
+	for _, issue := range kept {
+		numbers = append(numbers, issue.GetNumber())
+	}
+
+	if want := []int{14, 17}; !reflect.DeepEqual(numbers, want) {
+		t.Errorf("kept %v, want %v", numbers, want)
+	}
+
+	explanation := Explain(issues, decisions)
+
+	want := map[int][]CandidateDecision{
+		14: {
+			{Filter: "excluded", Keep: true, Reason: "not excluded"},
+			{Filter: "closed-by-pull", Keep: true, Reason: "pull request"},
+			{Filter: "in-comparison", Keep: true, Reason: "merged by commit c3"},
+		},
+		15: {
+			{Filter: "excluded", Keep: false, Reason: "excluded by number"},
+		},
+		16: {
+			{Filter: "excluded", Keep: false, Reason: "opened by dependabot[bot]"},
+		},
+		17: {
+			{Filter: "excluded", Keep: true, Reason: "not excluded"},
+			{Filter: "closed-by-pull", Keep: true, Reason: "pull request"},
+			{Filter: "in-comparison", Keep: true, Reason: "added, associated with commit c4"},
+		},
+	}
+
+	if len(explanation.Candidates) != len(want) {
+		t.Errorf("got %d candidates, want %d", len(explanation.Candidates), len(want))
+	}
+
+	for _, c := range explanation.Candidates {
+		if !reflect.DeepEqual(c.Decisions, want[c.Number]) {
+			t.Errorf("#%d: got decisions %+v, want %+v", c.Number, c.Decisions, want[c.Number])
+		}
+	}
+}
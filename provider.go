@@ -0,0 +1,123 @@
+package releasekit
+
+import (
+	"context"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// Provider is a forge hosting the repository, such as GitHub or GitLab, that
+// is both the source of the contents of a release and where the release is
+// published. Releases are represented using the GitHub types whichever forge
+// is used.
+type Provider interface {
+	Source
+
+	// ListTags lists the names of all the tags in the repository.
+	ListTags(ctx context.Context) ([]string, error)
+
+	// TagExists checks if the tag exists in the repository.
+	TagExists(ctx context.Context, tag string) (bool, error)
+
+	// GetDefaultBranch gets the name of the default branch of the repository.
+	GetDefaultBranch(ctx context.Context) (string, error)
+
+	// GetReleaseByTag returns the release for the given tag, or
+	// ErrReleaseNotFound if it doesn't exist.
+	GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error)
+
+	// CreateOrEditRelease creates the release if it doesn't have an ID, else it
+	// edits the existing release.
+	CreateOrEditRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error)
+
+	// UploadReleaseAssets uploads the files to the release as assets.
+	UploadReleaseAssets(ctx context.Context, release *github.RepositoryRelease, attachments []string) error
+
+	// CommitFile creates or updates the file at the path in the repository
+	// with the content, committing it to the branch with the message. If the
+	// branch is empty the default branch is used.
+	CommitFile(ctx context.Context, path, branch, message string, content []byte) error
+}
+
+// GitHubProvider is a Provider for a repository on GitHub.
+type GitHubProvider struct {
+	Source
+
+	Client *github.Client
+	Owner  string
+	Repo   string
+}
+
+// NewGitHubProvider creates a new provider for the repository on GitHub,
+// fetching the contents of releases from the source. If the source is nil, the
+// REST API is used.
+func NewGitHubProvider(c *github.Client, owner, repo string, source Source) *GitHubProvider {
+	if source == nil {
+		source = NewRESTSource(c, owner, repo)
+	}
+
+	return &GitHubProvider{Source: source, Client: c, Owner: owner, Repo: repo}
+}
+
+// ListTags lists the names of all the tags in the repository, using the source
+// if it can list them.
+func (p *GitHubProvider) ListTags(ctx context.Context) ([]string, error) {
+	if lister, ok := p.Source.(TagLister); ok {
+		return lister.ListTags(ctx)
+	}
+
+	return ListTags(ctx, p.Client, p.Owner, p.Repo)
+}
+
+// TagExists checks if the tag exists in the repository.
+func (p *GitHubProvider) TagExists(ctx context.Context, tag string) (bool, error) {
+	return TagExists(ctx, p.Client, p.Owner, p.Repo, tag)
+}
+
+// GetDefaultBranch gets the name of the default branch of the repository.
+func (p *GitHubProvider) GetDefaultBranch(ctx context.Context) (string, error) {
+	return GetDefaultBranch(ctx, p.Client, p.Owner, p.Repo)
+}
+
+// GetReleaseByTag returns the release for the given tag, or ErrReleaseNotFound
+// if it doesn't exist.
+func (p *GitHubProvider) GetReleaseByTag(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	return GetReleaseByTag(ctx, p.Client, p.Owner, p.Repo, tag)
+}
+
+// CreateOrEditRelease creates the release if it doesn't have an ID, else it
+// edits the existing release.
+func (p *GitHubProvider) CreateOrEditRelease(ctx context.Context, release *github.RepositoryRelease) (*github.RepositoryRelease, error) {
+	return CreateOrEditRelease(ctx, p.Client, p.Owner, p.Repo, release)
+}
+
+// UploadReleaseAssets uploads the files to the release as assets.
+func (p *GitHubProvider) UploadReleaseAssets(ctx context.Context, release *github.RepositoryRelease, attachments []string) error {
+	return UploadReleaseAssets(ctx, p.Client, p.Owner, p.Repo, release.GetID(), attachments)
+}
+
+// CommitFile creates or updates the file at the path in the repository with
+// the content, committing it to the branch with the message.
+func (p *GitHubProvider) CommitFile(ctx context.Context, path, branch, message string, content []byte) error {
+	return CommitFile(ctx, p.Client, p.Owner, p.Repo, path, branch, message, content)
+}
+
+// ListCommitFiles lists the names of the files changed by the commit.
+func (p *GitHubProvider) ListCommitFiles(ctx context.Context, sha string) ([]string, error) {
+	return ListCommitFiles(ctx, p.Client, p.Owner, p.Repo, sha)
+}
+
+// ListPullRequestsForCommits lists the merged pull requests associated with
+// each of the commits, using the source if it can list them.
+func (p *GitHubProvider) ListPullRequestsForCommits(ctx context.Context, shas []string) (map[string][]*github.PullRequest, error) {
+	if lister, ok := p.Source.(PullRequestLister); ok {
+		return lister.ListPullRequestsForCommits(ctx, shas)
+	}
+
+	return listPullRequestsForCommits(ctx, p.Client, p.Owner, p.Repo, shas)
+}
+
+// GetIssue gets the issue with the specified number in the repository.
+func (p *GitHubProvider) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	return GetIssue(ctx, p.Client, owner, repo, number)
+}
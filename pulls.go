@@ -2,16 +2,304 @@ package releasekit
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
 	"github.com/google/go-github/v18/github"
 )
 
-// GetPullRequest gets the pull request with the specified number.
-func GetPullRequest(c *github.Client, owner, repo string, number int) (*github.PullRequest, error) {
-	pr, _, err := c.PullRequests.Get(context.Background(), owner, repo, number)
+// DefaultConcurrency is the default number of pull requests fetched at the same
+// time. It is kept low to avoid hitting the secondary rate limits.
+const DefaultConcurrency = 4
+
+// GetPullRequest gets the pull request with the specified number, or
+// ErrPullNotFound if it doesn't exist.
+func GetPullRequest(ctx context.Context, c *github.Client, owner, repo string, number int) (*github.PullRequest, error) {
+	pr, _, err := c.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, wrapError(err, ErrPullNotFound)
+	}
+
+	return pr, nil
+}
+
+// ListCommitPullRequests lists the merged pull requests associated with the
+// commit, which are the pull requests the commit was merged, squashed or
+// rebased by, and the pull requests of any merged branches it was part of.
+func ListCommitPullRequests(ctx context.Context, c *github.Client, owner, repo, sha string) ([]*github.PullRequest, error) {
+	u := fmt.Sprintf("repos/%v/%v/commits/%v/pulls", owner, repo, sha)
+
+	req, err := c.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the commit pull requests API is a preview in older GitHub Enterprise
+	// Server versions
+	req.Header.Set("Accept", "application/vnd.github.groot-preview+json")
+
+	var pulls []*github.PullRequest
+
+	if _, err := c.Do(ctx, req, &pulls); err != nil {
+		return nil, wrapError(err, nil)
+	}
+
+	var merged []*github.PullRequest
+
+	for _, pr := range pulls {
+		// the merged field is only included when getting a single pull
+		// request
+		if pr.MergedAt != nil {
+			pr.Merged = github.Bool(true)
+			merged = append(merged, pr)
+		}
+	}
+
+	return merged, nil
+}
+
+// listPullRequestsForCommits lists the merged pull requests associated with
+// each of the commits using the REST API, listing up to DefaultConcurrency
+// commits at the same time.
+func listPullRequestsForCommits(ctx context.Context, c *github.Client, owner, repo string, shas []string) (map[string][]*github.PullRequest, error) {
+	var mu sync.Mutex
+
+	pulls := make(map[string][]*github.PullRequest)
+
+	err := each(ctx, DefaultConcurrency, len(shas), func(ctx context.Context, i int) error {
+		prs, err := ListCommitPullRequests(ctx, c, owner, repo, shas[i])
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		pulls[shas[i]] = prs
+		mu.Unlock()
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return pulls, nil
+}
+
+// pullRequestIssue converts the pull request to the issue for it, as returned
+// when listing issues.
+func pullRequestIssue(pr *github.PullRequest) *github.Issue {
+	issue := &github.Issue{
+		Number:    pr.Number,
+		State:     pr.State,
+		Title:     pr.Title,
+		Body:      pr.Body,
+		User:      pr.User,
+		ClosedAt:  pr.ClosedAt,
+		CreatedAt: pr.CreatedAt,
+		UpdatedAt: pr.UpdatedAt,
+		URL:       pr.IssueURL,
+		HTMLURL:   pr.HTMLURL,
+		PullRequestLinks: &github.PullRequestLinks{
+			URL:     pr.URL,
+			HTMLURL: pr.HTMLURL,
+		},
+	}
+
+	for _, l := range pr.Labels {
+		issue.Labels = append(issue.Labels, *l)
+	}
+
+	return issue
+}
+
+// PullRequestCache fetches the pull requests of a repository from a source, and
+// caches them so each pull request is only fetched once per run. It is safe for
+// concurrent use.
+type PullRequestCache struct {
+	source Source
+
+	// Concurrency is the number of pull requests fetched at the same time by
+	// Prefetch.
+	Concurrency int
+
+	mu      sync.Mutex
+	pulls   map[int]*github.PullRequest
+	commits map[string][]int
+}
+
+// NewPullRequestCache creates a new empty pull request cache for the source.
+func NewPullRequestCache(source Source) *PullRequestCache {
+	return &PullRequestCache{
+		source:      source,
+		Concurrency: DefaultConcurrency,
+		pulls:       make(map[int]*github.PullRequest),
+		commits:     make(map[string][]int),
+	}
+}
+
+// Get gets the pull request with the specified number, fetching it if it is
+// not already cached.
+func (pc *PullRequestCache) Get(ctx context.Context, number int) (*github.PullRequest, error) {
+	pc.mu.Lock()
+	pr, ok := pc.pulls[number]
+	pc.mu.Unlock()
+
+	if ok {
+		return pr, nil
+	}
+
+	pr, err := pc.source.GetPullRequest(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.mu.Lock()
+	pc.pulls[number] = pr
+	pc.mu.Unlock()
+
 	return pr, nil
 }
+
+// ListsCommits returns true if the source is a PullRequestLister, so the pull
+// requests associated with commits can be found.
+func (pc *PullRequestCache) ListsCommits() bool {
+	_, ok := pc.source.(PullRequestLister)
+	return ok
+}
+
+// ForCommit gets the merged pull requests associated with the commit, fetching
+// them if they are not already cached. If the source is not a
+// PullRequestLister no pull requests are returned.
+func (pc *PullRequestCache) ForCommit(ctx context.Context, sha string) ([]*github.PullRequest, error) {
+	if err := pc.PrefetchCommits(ctx, []string{sha}); err != nil {
+		return nil, err
+	}
+
+	pc.mu.Lock()
+	numbers := pc.commits[sha]
+	pc.mu.Unlock()
+
+	return pc.cached(numbers), nil
+}
+
+// cached returns the cached pull requests with the specified numbers.
+func (pc *PullRequestCache) cached(numbers []int) []*github.PullRequest {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	var pulls []*github.PullRequest
+
+	for _, number := range numbers {
+		pulls = append(pulls, pc.pulls[number])
+	}
+
+	return pulls
+}
+
+// Prefetch fetches the pull requests with the specified numbers that are not
+// already cached, fetching up to Concurrency pull requests at the same time.
+// The first error stops any remaining fetches and is returned.
+func (pc *PullRequestCache) Prefetch(ctx context.Context, numbers []int) error {
+	return each(ctx, pc.Concurrency, len(numbers), func(ctx context.Context, i int) error {
+		_, err := pc.Get(ctx, numbers[i])
+		return err
+	})
+}
+
+// PrefetchCommits fetches the pull requests associated with the commits that
+// are not already cached, listing them for all the commits at once.
+func (pc *PullRequestCache) PrefetchCommits(ctx context.Context, shas []string) error {
+	lister, ok := pc.source.(PullRequestLister)
+	if !ok {
+		return nil
+	}
+
+	var missing []string
+
+	pc.mu.Lock()
+
+	for _, sha := range shas {
+		if _, ok := pc.commits[sha]; !ok {
+			missing = append(missing, sha)
+		}
+	}
+
+	pc.mu.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	pulls, err := lister.ListPullRequestsForCommits(ctx, missing)
+	if err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for _, sha := range missing {
+		numbers := []int{}
+
+		for _, pr := range pulls[sha] {
+			numbers = append(numbers, pr.GetNumber())
+
+			if _, ok := pc.pulls[pr.GetNumber()]; !ok {
+				pc.pulls[pr.GetNumber()] = pr
+			}
+		}
+
+		pc.commits[sha] = numbers
+	}
+
+	return nil
+}
+
+// each calls fn for each index up to n, using up to the number of workers
+// goroutines. The first error stops any remaining calls and is returned.
+func each(ctx context.Context, workers, n int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	queue := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				if err := fn(ctx, i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		queue <- i
+	}
+
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,160 @@
+package releasekit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v18/github"
+)
+
+const (
+	// issueReferenceRegex matches an issue reference like #N, owner/repo#N or
+	// the URL of an issue on GitHub, GitLab or Gitea. Owners can include
+	// GitLab subgroups.
+	issueReferenceRegex = `(?:https?://[^\s/]+/([\w.-]+(?:/[\w.-]+)*)/(\w[\w.-]*)(?:/-)?/issues/|([\w.-]+(?:/[\w.-]+)*)/([\w.-]+)#|#)([0-9]+)\b`
+
+	// closingReferencesRegex matches a closing keyword followed by a list of
+	// issue references, such as "fixes #1, #2 and owner/repo#3".
+	closingReferencesRegex = `(?i)\b(?:close|closes|closed|resolve|resolves|resolved|fix|fixes|fixed):?\s+(` +
+		issueReferenceRegex + `(?:\s*(?:,|\band\b)\s*` + issueReferenceRegex + `)*)`
+)
+
+// Reference is a reference to an issue. References to issues in the same
+// repository have no owner or repository.
+type Reference struct {
+	Owner  string `json:"owner,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	Number int    `json:"number"`
+}
+
+// String returns the reference as #N, or owner/repo#N if it is to an issue in
+// another repository.
+func (r Reference) String() string {
+	if r.Owner == "" {
+		return fmt.Sprintf("#%d", r.Number)
+	}
+
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// In returns true if the reference is to an issue in the repository.
+func (r Reference) In(owner, repo string) bool {
+	return r.Owner == "" || (strings.EqualFold(r.Owner, owner) && strings.EqualFold(r.Repo, repo))
+}
+
+// ParseClosingReferences returns every issue the text closes, in the order they
+// appear. Each closing keyword can be followed by a list of references
+// separated by commas or "and", and references can be to issues in other
+// repositories, either as owner/repo#N or as the URL of the issue.
+func ParseClosingReferences(text string) []Reference {
+	closing, _ := regexp.Compile(closingReferencesRegex)
+	reference, _ := regexp.Compile(issueReferenceRegex)
+
+	var refs []Reference
+
+	seen := make(map[Reference]bool)
+
+	for _, list := range closing.FindAllStringSubmatch(text, -1) {
+		for _, matches := range reference.FindAllStringSubmatch(list[1], -1) {
+			ref := Reference{Owner: matches[1], Repo: matches[2]}
+			if ref.Owner == "" {
+				ref.Owner, ref.Repo = matches[3], matches[4]
+			}
+
+			ref.Number, _ = strconv.Atoi(matches[5])
+
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// closedIssueNumbers returns the numbers of the issues in the repository
+// closed by the text.
+func closedIssueNumbers(text, owner, repo string) []int {
+	var numbers []int
+
+	for _, ref := range ParseClosingReferences(text) {
+		if ref.In(owner, repo) {
+			numbers = append(numbers, ref.Number)
+		}
+	}
+
+	return numbers
+}
+
+// CrossRepositoryReferences returns the references to issues in other
+// repositories closed by the pull requests or the commits, in the order they
+// appear.
+func CrossRepositoryReferences(issues []*github.Issue, commits []github.RepositoryCommit, owner, repo string) []Reference {
+	var texts []string
+
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			texts = append(texts, issue.GetBody())
+		}
+	}
+
+	for _, c := range commits {
+		texts = append(texts, c.GetCommit().GetMessage())
+	}
+
+	var refs []Reference
+
+	seen := make(map[Reference]bool)
+
+	for _, text := range texts {
+		for _, ref := range ParseClosingReferences(text) {
+			// normalise the case so the same issue is only included once
+			key := Reference{Owner: strings.ToLower(ref.Owner), Repo: strings.ToLower(ref.Repo), Number: ref.Number}
+
+			if !ref.In(owner, repo) && !seen[key] {
+				seen[key] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// FetchReferencedIssues fetches the closed issues for the references to issues
+// in other repositories, setting the repository of each issue to the one it
+// was referenced in. Issues that are still open are left out, as are issues in
+// repositories that cannot be found or accessed.
+func FetchReferencedIssues(ctx context.Context, getter IssueGetter, refs []Reference) ([]*github.Issue, error) {
+	var issues []*github.Issue
+
+	for _, ref := range refs {
+		issue, err := getter.GetIssue(ctx, ref.Owner, ref.Repo, ref.Number)
+		if errors.Is(err, ErrIssueNotFound) || errors.Is(err, ErrPermissionDenied) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if issue.GetState() != "closed" || issue.IsPullRequest() {
+			continue
+		}
+
+		issue.Repository = &github.Repository{
+			Name:     github.String(ref.Repo),
+			FullName: github.String(ref.Owner + "/" + ref.Repo),
+			Owner:    &github.User{Login: github.String(ref.Owner)},
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,75 @@
+package releasekit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClosingReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Reference
+	}{
+		{
+			name: "no references",
+			text: "Refactor the parser",
+		},
+		{
+			name: "reference without a keyword",
+			text: "See #12 for details",
+		},
+		{
+			name: "single reference",
+			text: "Fixes #12",
+			want: []Reference{{Number: 12}},
+		},
+		{
+			name: "keyword variations",
+			text: "close #1\nresolved #2\nFIXED: #3",
+			want: []Reference{{Number: 1}, {Number: 2}, {Number: 3}},
+		},
+		{
+			name: "list separated by commas and and",
+			text: "Closes #1, #2 and #3",
+			want: []Reference{{Number: 1}, {Number: 2}, {Number: 3}},
+		},
+		{
+			name: "repeated keywords",
+			text: "Fixes #1, fixes #2",
+			want: []Reference{{Number: 1}, {Number: 2}},
+		},
+		{
+			name: "duplicates",
+			text: "Fixes #1, fixes #1",
+			want: []Reference{{Number: 1}},
+		},
+		{
+			name: "other repository",
+			text: "Fixes tombell/other#4",
+			want: []Reference{{Owner: "tombell", Repo: "other", Number: 4}},
+		},
+		{
+			name: "GitHub issue URL",
+			text: "Resolves https://github.com/tombell/other/issues/5",
+			want: []Reference{{Owner: "tombell", Repo: "other", Number: 5}},
+		},
+		{
+			name: "GitLab issue URL with subgroup",
+			text: "Closes https://gitlab.com/group/subgroup/project/-/issues/6",
+			want: []Reference{{Owner: "group/subgroup", Repo: "project", Number: 6}},
+		},
+		{
+			name: "number followed by text",
+			text: "Fixes #7abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseClosingReferences(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseClosingReferences(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package releasekit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// schema is the subset of JSON schema used by docs/release.schema.json.
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Required    []string           `json:"required"`
+	Properties  map[string]*schema `json:"properties"`
+	Items       *schema            `json:"items"`
+	Definitions map[string]*schema `json:"definitions"`
+}
+
+// validate checks the value against the schema, returning the problems found.
+// Properties that are not in the schema are problems too, so the schema is
+// kept up to date with the output.
+func (s *schema) validate(root *schema, path string, v interface{}) []string {
+	if s.Ref != "" {
+		def, ok := root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown $ref %s", path, s.Ref)}
+		}
+
+		return def.validate(root, path, v)
+	}
+
+	var problems []string
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object", path)}
+		}
+
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required %s", path, name))
+			}
+		}
+
+		for name, value := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s is not in the schema", path, name))
+				continue
+			}
+
+			problems = append(problems, prop.validate(root, path+"."+name, value)...)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array", path)}
+		}
+
+		for i, value := range arr {
+			problems = append(problems, s.Items.validate(root, fmt.Sprintf("%s[%d]", path, i), value)...)
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected a string", path)}
+		}
+
+		switch s.Format {
+		case "uri":
+			if u, err := url.Parse(str); err != nil || !u.IsAbs() {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a URI", path, str))
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a date-time", path, str))
+			}
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s: expected an integer", path)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean", path)}
+		}
+	}
+
+	return problems
+}
+
+func TestNotesMatchSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("docs/release.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root schema
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("could not parse schema: %s", err)
+	}
+
+	issue := &Item{
+		Number:     12,
+		Title:      "Crash on startup",
+		URL:        "https://github.com/tombell/releasekit/issues/12",
+		Author:     "octocat",
+		Labels:     []string{"bug"},
+		Highlights: []string{"bug"},
+		Repository: "tombell/issues",
+		Reason:     "issue closed by pull request #14",
+	}
+
+	pr := &Item{
+		Number:      14,
+		Title:       "Fix crash on startup",
+		URL:         "https://github.com/tombell/releasekit/pull/14",
+		Author:      "octocat",
+		PullRequest: true,
+		Section:     "Bug Fixes",
+		MergeCommit: "1a2b3c4d5e6f",
+		Reason:      "pull request merged in commit 1a2b3c4",
+		Fixes:       []*Item{issue},
+	}
+
+	flipped := *issue
+	flipped.FixedBy = []*Item{pr}
+
+	commit := &Item{
+		Number:         15,
+		Title:          "add endpoint",
+		URL:            "https://github.com/tombell/releasekit/pull/15",
+		Author:         "octocat",
+		Labels:         []string{"feat", BreakingLabel},
+		PullRequest:    true,
+		SHA:            "9f8e7d6c5b4a",
+		CommitURL:      "https://github.com/tombell/releasekit/commit/9f8e7d6c5b4a",
+		Type:           "feat",
+		Scope:          "api",
+		Breaking:       true,
+		BreakingChange: "the endpoint has moved",
+	}
+
+	notes := &Notes{
+		Previous:       "v0.1.0",
+		Next:           "v0.2.0",
+		PreviousCommit: "0a1b2c3d4e5f",
+		NextCommit:     "9f8e7d6c5b4a",
+		CompareURL:     "https://github.com/tombell/releasekit/compare/v0.1.0...v0.2.0",
+		Date:           time.Date(2020, 2, 2, 10, 0, 0, 0, time.UTC),
+		Items:          []*Item{pr, &flipped, commit},
+		Labels:         []string{"bug"},
+		Changed:        []string{"go.mod"},
+		ReleaseURL:     "https://github.com/tombell/releasekit/releases/tag/v0.2.0",
+	}
+
+	out, err := json.Marshal(notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, problem := range root.validate(&root, "$", v) {
+		t.Error(problem)
+	}
+}
@@ -0,0 +1,101 @@
+package releasekit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultOtherSection is the title of the catch-all section for items that do
+// not match any section rule.
+const DefaultOtherSection = "Other"
+
+// SectionRule maps a set of labels to a section title in the release notes.
+type SectionRule struct {
+	Title  string
+	Labels []string
+}
+
+// Section is a titled group of items in the release notes.
+type Section struct {
+	Title string
+	Items []*Item
+}
+
+// ParseSectionRule parses a section rule in the format LABEL[,LABEL...]=TITLE.
+func ParseSectionRule(s string) (*SectionRule, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("invalid section %q, expected LABEL[,LABEL...]=TITLE", s)
+	}
+
+	rule := &SectionRule{Title: strings.TrimSpace(parts[1])}
+
+	for _, label := range strings.Split(parts[0], ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			rule.Labels = append(rule.Labels, label)
+		}
+	}
+
+	if len(rule.Labels) == 0 {
+		return nil, fmt.Errorf("invalid section %q, no labels given", s)
+	}
+
+	return rule, nil
+}
+
+// GroupSections groups the items into sections using the rules. Sections are
+// ordered the same as the rules, and an item with labels matching more than one
+// rule is placed in the section of the first matching rule. Items matching no
+// rule are placed in a catch-all section with the other title (or
+// DefaultOtherSection if empty), which comes last. Empty sections are omitted,
+// and each item's section is set to the title of its section.
+func GroupSections(items []*Item, rules []*SectionRule, other string) []*Section {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	sections := make([]*Section, len(rules))
+	for i, rule := range rules {
+		sections[i] = &Section{Title: rule.Title}
+	}
+
+	if other == "" {
+		other = DefaultOtherSection
+	}
+
+	catchall := &Section{Title: other}
+
+	for _, item := range items {
+		section := catchall
+
+		for i, rule := range rules {
+			if item.hasAnyLabel(rule.Labels) {
+				section = sections[i]
+				break
+			}
+		}
+
+		section.Items = append(section.Items, item)
+		item.Section = section.Title
+	}
+
+	var grouped []*Section
+
+	for _, section := range append(sections, catchall) {
+		if len(section.Items) > 0 {
+			grouped = append(grouped, section)
+		}
+	}
+
+	return grouped
+}
+
+func (i *Item) hasAnyLabel(labels []string) bool {
+	for _, label := range labels {
+		if i.HasLabel(label) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package releasekit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSectionRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *SectionRule
+		wantErr bool
+	}{
+		{
+			name: "single label",
+			s:    "bug=Bug Fixes",
+			want: &SectionRule{Title: "Bug Fixes", Labels: []string{"bug"}},
+		},
+		{
+			name: "multiple labels",
+			s:    "feature, enhancement ,=New Features ",
+			want: &SectionRule{Title: "New Features", Labels: []string{"feature", "enhancement"}},
+		},
+		{
+			name: "title with equals sign",
+			s:    "docs=Docs = Guides",
+			want: &SectionRule{Title: "Docs = Guides", Labels: []string{"docs"}},
+		},
+		{
+			name:    "no title",
+			s:       "bug=",
+			wantErr: true,
+		},
+		{
+			name:    "no equals sign",
+			s:       "bug",
+			wantErr: true,
+		},
+		{
+			name:    "no labels",
+			s:       " , =Bug Fixes",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSectionRule(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSectionRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSectionRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupSections(t *testing.T) {
+	rules := []*SectionRule{
+		{Title: "Breaking Changes", Labels: []string{"breaking"}},
+		{Title: "Bug Fixes", Labels: []string{"bug", "fix"}},
+		{Title: "Documentation", Labels: []string{"docs"}},
+	}
+
+	tests := []struct {
+		name  string
+		rules []*SectionRule
+		other string
+		items []*Item
+		want  map[string][]int
+		order []string
+	}{
+		{
+			name:  "no rules",
+			items: []*Item{{Number: 1, Labels: []string{"bug"}}},
+		},
+		{
+			name:  "sections in rule order",
+			rules: rules,
+			items: []*Item{
+				{Number: 1, Labels: []string{"fix"}},
+				{Number: 2, Labels: []string{"breaking"}},
+				{Number: 3, Labels: []string{"bug"}},
+			},
+			want:  map[string][]int{"Breaking Changes": {2}, "Bug Fixes": {1, 3}},
+			order: []string{"Breaking Changes", "Bug Fixes"},
+		},
+		{
+			name:  "first matching rule",
+			rules: rules,
+			items: []*Item{
+				{Number: 1, Labels: []string{"docs", "bug", "breaking"}},
+				{Number: 2, Labels: []string{"docs", "bug"}},
+			},
+			want:  map[string][]int{"Breaking Changes": {1}, "Bug Fixes": {2}},
+			order: []string{"Breaking Changes", "Bug Fixes"},
+		},
+		{
+			name:  "other section",
+			rules: rules,
+			items: []*Item{
+				{Number: 1},
+				{Number: 2, Labels: []string{"docs"}},
+				{Number: 3, Labels: []string{"question"}},
+			},
+			want:  map[string][]int{"Documentation": {2}, DefaultOtherSection: {1, 3}},
+			order: []string{"Documentation", DefaultOtherSection},
+		},
+		{
+			name:  "other section title",
+			rules: rules,
+			other: "Miscellaneous",
+			items: []*Item{{Number: 1}},
+			want:  map[string][]int{"Miscellaneous": {1}},
+			order: []string{"Miscellaneous"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := GroupSections(tt.items, tt.rules, tt.other)
+
+			var order []string
+
+			for _, section := range sections {
+				order = append(order, section.Title)
+
+				var numbers []int
+
+				for _, item := range section.Items {
+					numbers = append(numbers, item.Number)
+
+					if item.Section != section.Title {
+						t.Errorf("#%d section = %q, want %q", item.Number, item.Section, section.Title)
+					}
+				}
+
+				if want := tt.want[section.Title]; !reflect.DeepEqual(numbers, want) {
+					t.Errorf("%s items = %v, want %v", section.Title, numbers, want)
+				}
+			}
+
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("sections = %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package releasekit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const semverRegex = `^(v?)(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`
+
+// Version is a semantic version parsed from a tag.
+type Version struct {
+	Prefix     string
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Metadata   string
+}
+
+// ParseVersion parses the tag as a semantic version, with an optional "v"
+// prefix.
+func ParseVersion(tag string) (*Version, error) {
+	r, _ := regexp.Compile(semverRegex)
+
+	matches := r.FindStringSubmatch(tag)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid semantic version %q", tag)
+	}
+
+	v := &Version{
+		Prefix:     matches[1],
+		Prerelease: matches[5],
+		Metadata:   matches[6],
+	}
+
+	v.Major, _ = strconv.Atoi(matches[2])
+	v.Minor, _ = strconv.Atoi(matches[3])
+	v.Patch, _ = strconv.Atoi(matches[4])
+
+	return v, nil
+}
+
+// String returns the version as a tag, including the prefix.
+func (v *Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+
+	if v.Metadata != "" {
+		s += "+" + v.Metadata
+	}
+
+	return s
+}
+
+// IsPrerelease returns true if the version has a prerelease suffix.
+func (v *Version) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// Compare compares the precedence of the versions, returning -1, 0 or 1 if the
+// version is lower than, equal to, or greater than the other version.
+func (v *Version) Compare(o *Version) int {
+	if c := compareInt(v.Major, o.Major); c != 0 {
+		return c
+	}
+
+	if c := compareInt(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+
+	if c := compareInt(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// Bump returns the next version for the type of change, without any
+// prerelease suffix. A prerelease of the version being bumped to is released
+// as is, e.g. bumping 1.1.0-rc.1 by minor is 1.1.0.
+func (v *Version) Bump(b Bump) *Version {
+	next := &Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+
+	if v.IsPrerelease() {
+		switch {
+		case b == BumpMajor && v.Minor == 0 && v.Patch == 0,
+			b == BumpMinor && v.Patch == 0,
+			b <= BumpPatch:
+			return next
+		}
+	}
+
+	switch b {
+	case BumpMajor:
+		next.Major++
+		next.Minor = 0
+		next.Patch = 0
+	case BumpMinor:
+		next.Minor++
+		next.Patch = 0
+	default:
+		next.Patch++
+	}
+
+	return next
+}
+
+// SortVersions sorts the versions in ascending order of precedence.
+func SortVersions(versions []*Version) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Compare(versions[j]) < 0
+	})
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	return compareInt(len(as), len(bs))
+}
@@ -0,0 +1,125 @@
+package releasekit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// Source is a source of the commits, issues and pull requests that make up the
+// contents of a release.
+type Source interface {
+	// GetCommitForTag gets the commit a tag is a reference to.
+	GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error)
+
+	// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+	GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error)
+
+	// GetFirstCommit gets the first commit to the repository.
+	GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error)
+
+	// GetComparison gets the commit comparison for the given base and head
+	// range.
+	GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error)
+
+	// FetchClosedIssuesSince fetches all closed issues and pull requests since
+	// the specified time.
+	FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error)
+
+	// GetPullRequest gets the pull request with the specified number, or
+	// ErrPullNotFound if it doesn't exist.
+	GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error)
+}
+
+// FileLister is implemented by sources that can list the files changed by a
+// commit, which is used when a comparison has too many files.
+type FileLister interface {
+	// ListCommitFiles lists the names of the files changed by the commit.
+	ListCommitFiles(ctx context.Context, sha string) ([]string, error)
+}
+
+// PullRequestLister is implemented by sources that can list the pull requests
+// associated with commits, which is used to find pull requests that were
+// squashed or rebased without their number in the commit message.
+type PullRequestLister interface {
+	// ListPullRequestsForCommits lists the merged pull requests associated
+	// with each of the commits, by SHA.
+	ListPullRequestsForCommits(ctx context.Context, shas []string) (map[string][]*github.PullRequest, error)
+}
+
+// TagLister is implemented by sources that can list the tags in the
+// repository, which is used instead of the REST API when working out the
+// previous or next version.
+type TagLister interface {
+	// ListTags lists the names of all the tags in the repository.
+	ListTags(ctx context.Context) ([]string, error)
+}
+
+// IssueGetter is implemented by sources that can get issues from other
+// repositories, which is used for issues closed in other repositories.
+type IssueGetter interface {
+	// GetIssue gets the issue with the specified number in the repository, or
+	// ErrIssueNotFound if it doesn't exist.
+	GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error)
+}
+
+// RESTSource is a Source that uses the GitHub REST API.
+type RESTSource struct {
+	Client *github.Client
+	Owner  string
+	Repo   string
+}
+
+// NewRESTSource creates a new source for the repository using the GitHub REST
+// API.
+func NewRESTSource(c *github.Client, owner, repo string) *RESTSource {
+	return &RESTSource{Client: c, Owner: owner, Repo: repo}
+}
+
+// GetCommitForTag gets the commit a tag is a reference to.
+func (s *RESTSource) GetCommitForTag(ctx context.Context, tag string) (*github.RepositoryCommit, error) {
+	return GetCommitForTag(ctx, s.Client, s.Owner, s.Repo, tag)
+}
+
+// GetCommit gets the commit for the given ref, such as a branch name or SHA.
+func (s *RESTSource) GetCommit(ctx context.Context, ref string) (*github.RepositoryCommit, error) {
+	return GetCommit(ctx, s.Client, s.Owner, s.Repo, ref)
+}
+
+// GetFirstCommit gets the first commit to the repository.
+func (s *RESTSource) GetFirstCommit(ctx context.Context) (*github.RepositoryCommit, error) {
+	return GetFirstCommit(ctx, s.Client, s.Owner, s.Repo)
+}
+
+// GetComparison gets the commit comparison for the given base and head range.
+func (s *RESTSource) GetComparison(ctx context.Context, base, head string) (*github.CommitsComparison, error) {
+	return GetComparison(ctx, s.Client, s.Owner, s.Repo, base, head)
+}
+
+// FetchClosedIssuesSince fetches all closed issues and pull requests since the
+// specified time.
+func (s *RESTSource) FetchClosedIssuesSince(ctx context.Context, since time.Time) ([]*github.Issue, error) {
+	return FetchClosedIssuesSince(ctx, s.Client, s.Owner, s.Repo, since)
+}
+
+// GetPullRequest gets the pull request with the specified number.
+func (s *RESTSource) GetPullRequest(ctx context.Context, number int) (*github.PullRequest, error) {
+	return GetPullRequest(ctx, s.Client, s.Owner, s.Repo, number)
+}
+
+// ListCommitFiles lists the names of the files changed by the commit.
+func (s *RESTSource) ListCommitFiles(ctx context.Context, sha string) ([]string, error) {
+	return ListCommitFiles(ctx, s.Client, s.Owner, s.Repo, sha)
+}
+
+// ListPullRequestsForCommits lists the merged pull requests associated with
+// each of the commits, making a request per commit.
+func (s *RESTSource) ListPullRequestsForCommits(ctx context.Context, shas []string) (map[string][]*github.PullRequest, error) {
+	return listPullRequestsForCommits(ctx, s.Client, s.Owner, s.Repo, shas)
+}
+
+// GetIssue gets the issue with the specified number in the repository.
+func (s *RESTSource) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	return GetIssue(ctx, s.Client, owner, repo, number)
+}
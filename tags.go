@@ -0,0 +1,92 @@
+package releasekit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v18/github"
+)
+
+// ListTags lists the names of all the tags in the repository.
+func ListTags(ctx context.Context, c *github.Client, owner, repo string) ([]string, error) {
+	opt := &github.ListOptions{PerPage: 100}
+
+	var allTags []string
+
+	for {
+		tags, resp, err := c.Repositories.ListTags(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, wrapError(err, nil)
+		}
+
+		for _, tag := range tags {
+			allTags = append(allTags, tag.GetName())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opt.Page = resp.NextPage
+	}
+
+	return allTags, nil
+}
+
+// ParseVersionTag parses the tag as a semantic version after the given prefix,
+// such as "api/" for tags like "api/v1.2.0". The prefix is kept as part of the
+// version's prefix.
+func ParseVersionTag(tag, prefix string) (*Version, error) {
+	if !strings.HasPrefix(tag, prefix) {
+		return nil, fmt.Errorf("tag %q does not have prefix %q", tag, prefix)
+	}
+
+	v, err := ParseVersion(strings.TrimPrefix(tag, prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	v.Prefix = prefix + v.Prefix
+
+	return v, nil
+}
+
+// VersionTags parses the tags with the given prefix as semantic versions,
+// ignoring any tags that are not valid semantic versions, and sorts them in
+// ascending order.
+func VersionTags(tags []string, prefix string) []*Version {
+	var versions []*Version
+
+	for _, tag := range tags {
+		if v, err := ParseVersionTag(tag, prefix); err == nil {
+			versions = append(versions, v)
+		}
+	}
+
+	SortVersions(versions)
+
+	return versions
+}
+
+// PreviousVersion returns the greatest version lower than the next version,
+// optionally ignoring prerelease versions, or nil if there is none.
+func PreviousVersion(versions []*Version, next *Version, prereleases bool) *Version {
+	var previous *Version
+
+	for _, v := range versions {
+		if v.IsPrerelease() && !prereleases {
+			continue
+		}
+
+		if v.Compare(next) >= 0 {
+			continue
+		}
+
+		if previous == nil || v.Compare(previous) > 0 {
+			previous = v
+		}
+	}
+
+	return previous
+}
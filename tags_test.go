@@ -0,0 +1,78 @@
+package releasekit
+
+import "testing"
+
+func TestPreviousVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []string
+		next        string
+		prereleases bool
+		want        string
+	}{
+		{
+			name: "no tags",
+			next: "v1.0.0",
+		},
+		{
+			name: "greatest lower version",
+			tags: []string{"v1.0.0", "v1.2.0", "v1.1.0", "v1.3.0"},
+			next: "v1.2.1",
+			want: "v1.2.0",
+		},
+		{
+			name: "next version is not previous",
+			tags: []string{"v1.0.0", "v1.1.0"},
+			next: "v1.1.0",
+			want: "v1.0.0",
+		},
+		{
+			name: "no lower version",
+			tags: []string{"v1.1.0"},
+			next: "v1.0.0",
+		},
+		{
+			name: "prereleases are ignored",
+			tags: []string{"v1.0.0", "v1.1.0-rc.1"},
+			next: "v1.1.0",
+			want: "v1.0.0",
+		},
+		{
+			name:        "prereleases are included",
+			tags:        []string{"v1.0.0", "v1.1.0-rc.1", "v1.1.0-rc.2"},
+			next:        "v1.1.0",
+			prereleases: true,
+			want:        "v1.1.0-rc.2",
+		},
+		{
+			name:        "earlier prerelease",
+			tags:        []string{"v1.0.0", "v1.1.0-rc.1", "v1.1.0-rc.2"},
+			next:        "v1.1.0-rc.2",
+			prereleases: true,
+			want:        "v1.1.0-rc.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := ParseVersion(tt.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := PreviousVersion(VersionTags(tt.tags, ""), next, tt.prereleases)
+
+			if got == nil {
+				if tt.want != "" {
+					t.Errorf("PreviousVersion(%v, %s) = nil, want %s", tt.tags, tt.next, tt.want)
+				}
+
+				return
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("PreviousVersion(%v, %s) = %s, want %q", tt.tags, tt.next, got, tt.want)
+			}
+		})
+	}
+}
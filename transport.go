@@ -0,0 +1,345 @@
+package releasekit
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is the default number of times a request is retried.
+	DefaultMaxRetries = 5
+
+	// DefaultMaxWait is the default longest time to wait for a rate limit to
+	// reset.
+	DefaultMaxWait = time.Hour
+
+	// DefaultBackoff is the default delay before the first retry of a server
+	// error.
+	DefaultBackoff = time.Second
+
+	// secondaryRateLimitWait is how long to wait after hitting a secondary rate
+	// limit without a Retry-After header, as advised by GitHub.
+	secondaryRateLimitWait = time.Minute
+)
+
+// NewHTTPTransport creates a new transport with the same settings as
+// http.DefaultTransport, trusting the certificates in the PEM encoded CA bundle
+// file as well as the system certificates, and making requests through the
+// proxy URL. If the CA bundle or proxy are empty they aren't changed, so the
+// proxy is taken from the environment.
+func NewHTTPTransport(caBundle, proxy string) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if caBundle != "" {
+		data, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", caBundle)
+		}
+
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	return t, nil
+}
+
+// RetryTransport is a http.RoundTripper that waits and retries requests that
+// hit a rate limit, or failed with a transient server error, and counts the
+// number of requests made.
+//
+// Primary rate limits are waited on until they reset, secondary rate limits are
+// waited on for the time given by the Retry-After header, and server errors for
+// idempotent requests are retried with jittered exponential backoff. A
+// successful response that exhausts the primary rate limit is returned straight
+// away, and the next request waits until the limit resets instead.
+type RetryTransport struct {
+	// Base is the transport used to make requests. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// MaxRetries is the number of times a request is retried.
+	MaxRetries int
+
+	// MaxWait is the longest time to wait for a rate limit to reset. Rate
+	// limits that reset later are returned as errors.
+	MaxWait time.Duration
+
+	// Backoff is the delay before the first retry of a server error, which
+	// doubles for each retry.
+	Backoff time.Duration
+
+	// OnWait is called, if not nil, before waiting to retry a request or for a
+	// rate limit to reset.
+	OnWait func(wait time.Duration, reason string)
+
+	requests int64
+
+	mu    sync.Mutex
+	reset time.Time
+}
+
+// NewRetryTransport creates a new retry transport that makes requests using the
+// base transport, with the default retry limits.
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Base:       base,
+		MaxRetries: DefaultMaxRetries,
+		MaxWait:    DefaultMaxWait,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// Requests returns the number of requests made, including retries.
+func (t *RetryTransport) Requests() int64 {
+	return atomic.LoadInt64(&t.requests)
+}
+
+// RoundTrip makes the request, retrying it if needed.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForReset(req.Context()); err != nil {
+			return nil, err
+		}
+
+		r, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		atomic.AddInt64(&t.requests, 1)
+
+		resp, err := base.RoundTrip(r)
+
+		wait, reason, retry := shouldRetry(r, resp, err, backoff(t.Backoff, attempt))
+
+		if !retry && wait > 0 && wait <= t.MaxWait {
+			t.mu.Lock()
+			t.reset = time.Now().Add(wait)
+			t.mu.Unlock()
+
+			// the GitHub client refuses to make requests until the reset time
+			// in the response, so it is removed to let the next request wait
+			resp.Header.Del("X-RateLimit-Reset")
+
+			return resp, nil
+		}
+
+		if !retry || wait > t.MaxWait {
+			return resp, err
+		}
+
+		if attempt >= t.MaxRetries || !canRewind(req) {
+			return resp, err
+		}
+
+		if resp != nil {
+			discardBody(resp)
+		}
+
+		t.notify(wait, reason)
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForReset waits until the primary rate limit resets, if a previous
+// response exhausted it.
+func (t *RetryTransport) waitForReset(ctx context.Context) error {
+	t.mu.Lock()
+	wait := time.Until(t.reset)
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	t.notify(wait, "rate limit exhausted")
+
+	return sleep(ctx, wait)
+}
+
+func (t *RetryTransport) notify(wait time.Duration, reason string) {
+	if t.OnWait != nil {
+		t.OnWait(wait, reason)
+	}
+}
+
+// shouldRetry decides whether the request should be retried, and how long to
+// wait before doing so, waiting for the backoff after a failure. A wait without
+// a retry means the response exhausted the rate limit, and the next request
+// should wait.
+func shouldRetry(req *http.Request, resp *http.Response, err error, backoff time.Duration) (time.Duration, string, bool) {
+	if err != nil {
+		if req.Context().Err() != nil || !idempotent(req.Method) {
+			return 0, "", false
+		}
+
+		return backoff, "request failed: " + err.Error(), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if wait, ok := retryAfter(resp); ok {
+			return wait, "secondary rate limit", true
+		}
+
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return untilReset(resp), "rate limit", true
+		}
+
+		if isSecondaryRateLimit(resp) {
+			return secondaryRateLimitWait, "secondary rate limit", true
+		}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if idempotent(req.Method) {
+			return backoff, resp.Status, true
+		}
+	}
+
+	if resp.StatusCode < 300 && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return untilReset(resp), "rate limit exhausted", false
+	}
+
+	return 0, "", false
+}
+
+// rewindRequest returns the request to make for the attempt, with a fresh copy
+// of the body for retries.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	r := req.Clone(req.Context())
+	r.Body = body
+
+	return r, nil
+}
+
+// canRewind returns true if the request can be made again.
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// retryAfter returns the wait given by the Retry-After header, if there is one.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		return time.Until(date), true
+	}
+
+	return 0, false
+}
+
+// untilReset returns the wait until the primary rate limit resets.
+func untilReset(resp *http.Response) time.Duration {
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return secondaryRateLimitWait
+	}
+
+	// wait an extra second in case of clock differences
+	return time.Until(time.Unix(reset, 0)) + time.Second
+}
+
+// isSecondaryRateLimit checks the body of the response for the secondary rate
+// limit message, leaving the body intact to be read again.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	if err != nil {
+		return false
+	}
+
+	body := strings.ToLower(string(data))
+
+	return strings.Contains(body, "secondary rate limit") || strings.Contains(body, "abuse detection")
+}
+
+// backoff returns the jittered exponential backoff for the attempt, starting
+// from the base delay.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << uint(attempt)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func discardBody(resp *http.Response) {
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,257 @@
+package releasekit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 5; attempt++ {
+		max := DefaultBackoff << uint(attempt)
+
+		for i := 0; i < 100; i++ {
+			if d := backoff(DefaultBackoff, attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name   string
+		method string
+		status int
+		header http.Header
+		body   string
+		wait   time.Duration
+		retry  bool
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			status: http.StatusOK,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "server error",
+			method: http.MethodGet,
+			status: http.StatusBadGateway,
+			wait:   DefaultBackoff,
+			retry:  true,
+		},
+		{
+			name:   "server error for non-idempotent request",
+			method: http.MethodPost,
+			status: http.StatusBadGateway,
+		},
+		{
+			name:   "retry after",
+			method: http.MethodPost,
+			status: http.StatusForbidden,
+			header: http.Header{"Retry-After": {"30"}},
+			wait:   30 * time.Second,
+			retry:  true,
+		},
+		{
+			name:   "rate limit",
+			method: http.MethodGet,
+			status: http.StatusForbidden,
+			header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {reset}},
+			wait:   time.Hour,
+			retry:  true,
+		},
+		{
+			name:   "secondary rate limit",
+			method: http.MethodGet,
+			status: http.StatusForbidden,
+			body:   `{"message": "You have exceeded a secondary rate limit."}`,
+			wait:   secondaryRateLimitWait,
+			retry:  true,
+		},
+		{
+			name:   "forbidden",
+			method: http.MethodGet,
+			status: http.StatusForbidden,
+			body:   `{"message": "Resource not accessible by integration"}`,
+		},
+		{
+			name:   "rate limit exhausted",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {reset}},
+			wait:   time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "https://api.github.com/", nil)
+
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     header,
+				Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			wait, _, retry := shouldRetry(req, resp, nil, DefaultBackoff)
+
+			if retry != tt.retry {
+				t.Errorf("retry = %t, want %t", retry, tt.retry)
+			}
+
+			// backoffs are jittered and resets are rounded to the second
+			if wait > tt.wait+2*time.Second || wait < tt.wait/2 {
+				t.Errorf("wait = %s, want about %s", wait, tt.wait)
+			}
+		})
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		failures int64
+		status   int
+		requests int64
+	}{
+		{
+			name:     "success",
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			requests: 1,
+		},
+		{
+			name:     "retried until success",
+			method:   http.MethodGet,
+			failures: 2,
+			status:   http.StatusOK,
+			requests: 3,
+		},
+		{
+			name:     "retries run out",
+			method:   http.MethodGet,
+			failures: 10,
+			status:   http.StatusBadGateway,
+			requests: DefaultMaxRetries + 1,
+		},
+		{
+			name:     "non-idempotent request is not retried",
+			method:   http.MethodPost,
+			failures: 2,
+			status:   http.StatusBadGateway,
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var served int64
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt64(&served, 1) <= tt.failures {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+			}))
+			defer srv.Close()
+
+			var waits int64
+
+			transport := NewRetryTransport(nil)
+			transport.Backoff = time.Millisecond
+			transport.OnWait = func(wait time.Duration, reason string) {
+				atomic.AddInt64(&waits, 1)
+			}
+
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+
+			if transport.Requests() != tt.requests || served != tt.requests {
+				t.Errorf("made %d requests (%d served), want %d", transport.Requests(), served, tt.requests)
+			}
+
+			if waits != tt.requests-1 {
+				t.Errorf("waited %d times, want %d", waits, tt.requests-1)
+			}
+		})
+	}
+}
+
+func TestRetryTransportWaitsForExhaustedRateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Second)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	}))
+	defer srv.Close()
+
+	var reasons []string
+
+	transport := NewRetryTransport(nil)
+	transport.OnWait = func(wait time.Duration, reason string) {
+		reasons = append(reasons, reason)
+	}
+
+	client := &http.Client{Transport: transport}
+
+	start := time.Now()
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("first request took %s, want it returned straight away", elapsed)
+	}
+
+	if resp.Header.Get("X-RateLimit-Reset") != "" {
+		t.Errorf("reset header was not removed from the response")
+	}
+
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if time.Now().Before(reset) {
+		t.Errorf("second request was made before the rate limit reset")
+	}
+
+	if len(reasons) != 1 || reasons[0] != "rate limit exhausted" {
+		t.Errorf("waited for %v, want [rate limit exhausted]", reasons)
+	}
+}